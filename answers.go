@@ -0,0 +1,161 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Built-in options of EnableNonInteractive
+const (
+	yesOption            = "yes"
+	nonInteractiveOption = "non-interactive"
+	answersOption        = "answers"
+)
+
+// Prompts can be answered without a user, e.g. in CI, by an answers file or by environment
+// variables. Every prompt has a key: the key of ReadInput("Project name: ") is
+// "project-name", and its answer is read from the variable ANSWER_PROJECT_NAME, or from the
+// "project-name" entry of the answers file. Variables take precedence over the file.
+var (
+	answersMu       sync.Mutex
+	answerValues    = map[string]string{}
+	nonInteractive  bool
+	assumeYes       bool
+	nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// EnableNonInteractive adds the built-in options "--answers <file>", "--non-interactive"
+// and "--yes" (or "-y") to every command. In non-interactive mode, prompts use their
+// scripted answer or their default, and fail if they have neither. "--yes" also answers
+// yes to every confirmation.
+func (cli *cli) EnableNonInteractive() {
+	cli.addBuiltin(answersOption, "", reflect.String, "YAML or JSON file with the answers to the prompts, by prompt key")
+	cli.addBuiltin(nonInteractiveOption, "", reflect.Bool, "Never prompt. Prompts use their answer from the answers file, or their default")
+	cli.addBuiltin(yesOption, "y", reflect.Bool, "Answer yes to every confirmation, and never prompt")
+}
+
+// LoadAnswers reads the answers to the prompts from a YAML or JSON file that maps prompt
+// keys to answers. Lists are answers to MultiSelect.
+func LoadAnswers(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Could not read the answers file '%s'. %s", path, err.Error())
+	}
+
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return fmt.Errorf("Could not parse the answers file '%s'. %s", path, err.Error())
+	}
+
+	answersMu.Lock()
+	defer answersMu.Unlock()
+	for key, value := range raw {
+		if list, ok := value.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			answerValues[promptKey(key)] = strings.Join(items, ",")
+		} else {
+			answerValues[promptKey(key)] = fmt.Sprint(value)
+		}
+	}
+	return nil
+}
+
+// SetNonInteractive enables the non-interactive mode of the prompts. If yes is true,
+// confirmations are answered with yes.
+func SetNonInteractive(yes bool) {
+	answersMu.Lock()
+	defer answersMu.Unlock()
+	nonInteractive = true
+	assumeYes = yes
+}
+
+// configureAnswers applies the built-in options of EnableNonInteractive
+func (ctx *Context) configureAnswers() error {
+	if path, _ := ctx.builtinValue(answersOption).(string); path != "" {
+		if err := LoadAnswers(path); err != nil {
+			return err
+		}
+	}
+	if ctx.builtinValue(yesOption) == true {
+		SetNonInteractive(true)
+	} else if ctx.builtinValue(nonInteractiveOption) == true {
+		SetNonInteractive(false)
+	}
+	return nil
+}
+
+// promptKey converts a prompt message to its key, e.g. "Project name: " to "project-name"
+func promptKey(message string) string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(message), "-"), "-")
+}
+
+func answerVariable(key string) string {
+	return "ANSWER_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// lookupAnswer returns the scripted answer to the prompt key
+func lookupAnswer(key string) (string, bool) {
+	if answer, ok := os.LookupEnv(answerVariable(key)); ok {
+		return answer, true
+	}
+
+	answersMu.Lock()
+	defer answersMu.Unlock()
+	answer, ok := answerValues[key]
+	return answer, ok
+}
+
+func isNonInteractive() bool {
+	answersMu.Lock()
+	defer answersMu.Unlock()
+	return nonInteractive
+}
+
+func isAssumeYes() bool {
+	answersMu.Lock()
+	defer answersMu.Unlock()
+	return assumeYes
+}
+
+// noAnswerError is returned by prompts without an answer or default in non-interactive mode
+func noAnswerError(key string) error {
+	return fmt.Errorf("No answer for the prompt '%s' in non-interactive mode. Set %s, or add '%s' to the answers file", key, answerVariable(key), key)
+}
+
+// invalidAnswerError is returned for scripted answers that are not valid
+func invalidAnswerError(key string, err error) error {
+	return fmt.Errorf("Invalid answer for the prompt '%s'. %s", key, err.Error())
+}
+
+// scriptedAnswer returns the answer to a prompt that takes any text, if it must not be
+// asked: either the answer is scripted, or the CLI is non-interactive
+func scriptedAnswer(key, prompt string, hidden bool) (string, bool, error) {
+	if answer, ok := lookupAnswer(key); ok {
+		if hidden {
+			printAnswer(prompt, "")
+		} else {
+			printAnswer(prompt, answer)
+		}
+		return answer, true, nil
+	}
+	if isNonInteractive() {
+		return "", true, noAnswerError(key)
+	}
+	return "", false, nil
+}
+
+// printAnswer shows a scripted answer after its prompt, as if it was typed
+func printAnswer(prompt, answer string) {
+	if prompt != "" {
+		fmt.Println(strings.TrimRight(prompt, " ") + " " + answer)
+	}
+}
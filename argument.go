@@ -15,6 +15,7 @@ type argument struct {
 	value       interface{}
 	populated   bool
 	required    bool
+	secret      bool
 	kind        reflect.Kind
 	description string
 }
@@ -49,6 +50,9 @@ func buildArguments(c *Command) []*argument {
 		if required, exists := field.Tag.Lookup("required"); exists && required == "true" {
 			arg.required = true
 		}
+		if secret, exists := field.Tag.Lookup("secret"); exists && secret == "true" {
+			arg.secret = true
+		}
 		if description, exists := field.Tag.Lookup("description"); exists {
 			arg.description = description
 		}
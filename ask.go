@@ -0,0 +1,178 @@
+package gocli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// PromptValue lists the types a Prompt can parse answers into.
+type PromptValue interface {
+	~string | ~bool | ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~float32 | ~float64
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Prompt asks for a single typed value, e.g.
+//
+//	port, err := gocli.NewPrompt[int]("Port").Default(8080).Ask()
+//
+// Answers are parsed like option values. time.Duration answers use the syntax of
+// time.ParseDuration, e.g. "1m30s".
+type Prompt[T PromptValue] struct {
+	message    string
+	key        string
+	def        *T
+	validate   func(T) error
+	maxRetries int
+	hidden     bool
+}
+
+// NewPrompt returns a prompt that shows message.
+func NewPrompt[T PromptValue](message string) *Prompt[T] {
+	return &Prompt[T]{message: message}
+}
+
+// Default sets the value used when the answer is empty. It is shown in brackets after the
+// message. Without a default an answer is required.
+func (p *Prompt[T]) Default(def T) *Prompt[T] {
+	p.def = &def
+	return p
+}
+
+// Validate sets a function that rejects parsed answers by returning an error.
+func (p *Prompt[T]) Validate(validate func(T) error) *Prompt[T] {
+	p.validate = validate
+	return p
+}
+
+// MaxRetries sets how many times the user is asked again after an invalid answer. Zero
+// means no limit.
+func (p *Prompt[T]) MaxRetries(retries int) *Prompt[T] {
+	p.maxRetries = retries
+	return p
+}
+
+// Key sets the key of the prompt in the answers source. Defaults to the key derived from
+// the message, e.g. "project-name" for "Project name".
+func (p *Prompt[T]) Key(key string) *Prompt[T] {
+	p.key = key
+	return p
+}
+
+// Hidden hides the answer while it is typed, e.g. for passwords.
+func (p *Prompt[T]) Hidden() *Prompt[T] {
+	p.hidden = true
+	return p
+}
+
+// Ask prompts until the answer is valid, and returns it. Invalid answers are explained in
+// red before prompting again.
+func (p *Prompt[T]) Ask() (T, error) {
+	var zero T
+
+	key := p.key
+	if key == "" {
+		key = promptKey(p.message)
+	}
+	if answer, ok := lookupAnswer(key); ok {
+		if p.hidden {
+			printAnswer(p.label(), "")
+		} else {
+			printAnswer(p.label(), answer)
+		}
+		value, err := p.parse(answer)
+		if err != nil {
+			return zero, invalidAnswerError(key, err)
+		}
+		return value, nil
+	}
+	if isNonInteractive() {
+		if p.def == nil {
+			return zero, noAnswerError(key)
+		}
+		return p.parse("")
+	}
+
+	for attempt := 0; ; attempt++ {
+		line, err := p.read()
+		if err != nil {
+			fmt.Println()
+			return zero, err
+		}
+
+		value, err := p.parse(line)
+		if err == nil {
+			return value, nil
+		}
+		fmt.Println(Red("%s", err.Error()))
+
+		if p.maxRetries > 0 && attempt >= p.maxRetries {
+			return zero, fmt.Errorf("Too many invalid answers to '%s'. %s", p.message, err.Error())
+		}
+	}
+}
+
+func (p *Prompt[T]) read() (string, error) {
+	if p.hidden {
+		return readHiddenLine(p.label(), false)
+	}
+	fmt.Print(p.label())
+	return readLine()
+}
+
+func (p *Prompt[T]) label() string {
+	if p.def == nil || fmt.Sprint(*p.def) == "" {
+		return fmt.Sprintf("%s: ", p.message)
+	}
+	if p.hidden {
+		return fmt.Sprintf("%s [********]: ", p.message)
+	}
+	return fmt.Sprintf("%s [%v]: ", p.message, *p.def)
+}
+
+// parse converts and validates an answer
+func (p *Prompt[T]) parse(answer string) (T, error) {
+	var value T
+	if strings.TrimSpace(answer) == "" {
+		if p.def == nil {
+			return value, fmt.Errorf("A value is required")
+		}
+		value = *p.def
+	} else {
+		parsed, err := castType(answer, reflect.TypeOf(value))
+		if err != nil {
+			return value, err
+		}
+		value = parsed.(T)
+	}
+
+	if p.validate != nil {
+		if err := p.validate(value); err != nil {
+			return value, err
+		}
+	}
+	return value, nil
+}
+
+// castType parses s into a value of type t, like cast does for option values. It also
+// supports time.Duration
+func castType(s string, t reflect.Type) (interface{}, error) {
+	if t == durationType {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("Expected a duration like '1m30s', got '%s'", s)
+		}
+		return reflect.ValueOf(d).Convert(t).Interface(), nil
+	}
+
+	if t.Kind() != reflect.String {
+		s = strings.TrimSpace(s)
+	}
+	v, err := cast(s, t.Kind())
+	if err != nil {
+		return nil, err
+	}
+	return reflect.ValueOf(v).Convert(t).Interface(), nil
+}
@@ -1,23 +1,40 @@
 package gocli
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 
-	"golang.org/x/crypto/ssh/terminal"
+	"golang.org/x/term"
 )
 
 type BashProcess struct {
 	command       *exec.Cmd
+	cmd           string
 	stdinReader   *stdinReader
+	activeStdin   *stdinReader
 	stdoutHandler StdHandler
 	stderrHandler StdHandler
 	running       bool
+	expect        *expectBuffer
+	pty           bool
+	retry         *RetryPolicy
+	limits        *ResourceLimits
+	env           []string
+	args          []string
+	argv0         string
+	usage         *ResourceUsage
+	startTime     time.Time
+	ownGroup      bool
+	stdinPreload  []string
+	afterWait     []func()
+	mu            sync.Mutex
 }
 
 // StdHandler is a user defined function to handle the contents of cmd.Stdout and
@@ -40,16 +57,29 @@ func (c *customStdWriter) Write(p []byte) (int, error) {
 
 // StdinReader
 type stdinReader struct {
-	lines chan []byte
+	lines   chan []byte
+	done    chan struct{}
+	pending []byte
 }
 
 func (s *stdinReader) Read(p []byte) (int, error) {
-	line, ok := <-s.lines
-	if !ok {
-		return 0, io.EOF
+	if len(s.pending) == 0 {
+		// lines that are already queued are delivered before io.EOF
+		select {
+		case line := <-s.lines:
+			s.pending = line
+		default:
+			select {
+			case line := <-s.lines:
+				s.pending = line
+			case <-s.done:
+				return 0, io.EOF
+			}
+		}
 	}
-	return copy(p, line), nil
-
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
 }
 
 func (b *BashProcess) HandleStdout(handler StdHandler) {
@@ -72,6 +102,7 @@ func (b *BashProcess) CustomStdinWithBufferSize(preload []string, bufferLines in
 		preload = []string{}
 	}
 
+	b.stdinPreload = preload
 	b.stdinReader = &stdinReader{}
 	b.stdinReader.lines = make(chan []byte, bufferLines)
 	for _, line := range preload {
@@ -84,8 +115,16 @@ func (b *BashProcess) CustomStdin(preload []string) {
 }
 
 func (b *BashProcess) Stdin(line string) {
-	if b.running && b.stdinReader != nil && b.stdinReader.lines != nil {
-		b.stdinReader.lines <- []byte(line)
+	b.mu.Lock()
+	reader := b.stdinReader
+	running := b.running
+	b.mu.Unlock()
+
+	if running && reader != nil {
+		select {
+		case reader.lines <- []byte(line):
+		case <-reader.done:
+		}
 	}
 }
 
@@ -105,50 +144,216 @@ func (b *BashProcess) Process() *os.Process {
 	return nil
 }
 
+// UsePTY runs the process in a pseudo-terminal, so that it behaves as if it was started
+// from an interactive terminal (colors, progress bars, prompts). Stdout and stderr are
+// merged and passed to the stdout handler. Only supported on Linux.
+func (b *BashProcess) UsePTY() *BashProcess {
+	if !b.running {
+		b.pty = true
+	}
+	return b
+}
+
+// Env adds an environment variable to the process. The process also inherits the
+// environment of the CLI.
+func (b *BashProcess) Env(key, value string) *BashProcess {
+	if !b.running {
+		b.env = append(b.env, key+"="+value)
+	}
+	return b
+}
+
+// Args sets the positional parameters of the command, i.e. $1, $2, ...
+func (b *BashProcess) Args(args ...string) *BashProcess {
+	if !b.running {
+		b.args = args
+	}
+	return b
+}
+
+// bashCommand builds the bash invocation of cmd
+func (b *BashProcess) bashCommand(cmd string) *exec.Cmd {
+	args := []string{"bash", "-c", "-e", b.limitsScript() + cmd}
+	if len(b.args) > 0 || b.argv0 != "" {
+		argv0 := b.argv0
+		if argv0 == "" {
+			argv0 = "bash"
+		}
+		args = append(append(args, argv0), b.args...)
+	}
+	if b.limits != nil && b.limits.Nice != 0 {
+		args = append([]string{"nice", "-n", fmt.Sprint(b.limits.Nice)}, args...)
+	}
+
+	command := exec.Command(args[0], args[1:]...)
+	if len(b.env) > 0 {
+		command.Env = append(os.Environ(), b.env...)
+	}
+	return command
+}
+
+// Cmd sets the command that is executed by Run. It returns the process so that calls
+// can be chained, e.g. Bash().Cmd("ls -la").Run()
+func (b *BashProcess) Cmd(cmd string) *BashProcess {
+	if !b.running {
+		b.cmd = cmd
+	}
+	return b
+}
+
+// Run executes the command that was set with Cmd.
+func (b *BashProcess) Run() error {
+	return b.Exec(b.cmd)
+}
+
 func (b *BashProcess) Exec(cmd string) error {
-	if b.running {
-		return fmt.Errorf("Cannot exec command. Already running")
+	if b.retry != nil {
+		return b.execWithRetry(cmd)
 	}
 
-	b.command = exec.Command("bash", "-c", "-e", cmd)
+	if err := b.start(cmd, b.stdin(), b.stdout(), b.stderr()); err != nil {
+		return err
+	}
+	return b.wait()
+}
 
-	// if no stdout handler is defined, then default to printing to stdout
+// stdout returns the writer for the cmd output. If no stdout handler is defined, then
+// default to printing to stdout
+func (b *BashProcess) stdout() io.Writer {
 	if b.stdoutHandler == nil {
-		b.command.Stdout = os.Stdout
-	} else {
-		b.command.Stdout = &customStdWriter{
-			handler: b.stdoutHandler,
-		}
+		return os.Stdout
 	}
+	return &customStdWriter{handler: b.stdoutHandler}
+}
 
-	// if no stderr handler is defined, then default to printing to stderr
+// stderr returns the writer for the cmd errors. If no stderr handler is defined, then
+// default to printing to stderr
+func (b *BashProcess) stderr() io.Writer {
 	if b.stderrHandler == nil {
-		b.command.Stderr = os.Stderr
-	} else {
-		b.command.Stderr = &customStdWriter{
-			handler: b.stderrHandler,
-		}
+		return os.Stderr
 	}
+	return &customStdWriter{handler: b.stderrHandler}
+}
 
-	// if no inputs overwrite stdin, then default to reading from stdin
+// stdin returns the reader for the cmd input. If no inputs overwrite stdin, then
+// default to reading from stdin
+func (b *BashProcess) stdin() io.Reader {
 	if b.stdinReader == nil {
-		b.command.Stdin = os.Stdin
-	} else {
-		b.command.Stdin = b.stdinReader
+		return os.Stdin
+	}
+	return b.stdinReader
+}
+
+func (b *BashProcess) start(cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
+	if b.running {
+		return fmt.Errorf("Cannot exec command. Already running")
 	}
-	b.running = true
 
-	// check if process has exited
-	go func() {
-		for b.command.ProcessState.ExitCode() < 0 {
+	// processes that don't read from the terminal get their own process group, so that
+	// signals can be forwarded to everything they start. In watch mode, they stay in the
+	// group of the run, which is stopped as a whole
+	b.command = b.bashCommand(cmd)
+	b.ownGroup = b.pty || (!isTerminal(stdin) && !isWatchRun())
+	configureProcess(b.command, b.ownGroup && !b.pty)
+	b.afterWait = nil
+	b.usage = nil
+	b.startTime = time.Now()
+	if b.pty {
+		if err := b.startPTY(stdin, stdout); err != nil {
+			return err
 		}
-		b.running = false
-		if b.stdinReader.lines != nil {
-			close(b.stdinReader.lines)
+		b.running = true
+		trackProcess(b)
+		return nil
+	}
+
+	b.command.Stdout = stdout
+	b.command.Stderr = stderr
+
+	// the custom stdin is copied by our own goroutine, because exec.Cmd.Wait would
+	// otherwise block until the reader returns io.EOF
+	var stdinPipe io.WriteCloser
+	reader, isCustom := stdin.(*stdinReader)
+	if isCustom {
+		pipe, err := b.command.StdinPipe()
+		if err != nil {
+			return err
 		}
-	}()
-	return b.command.Run()
+		stdinPipe = pipe
+		reader.done = make(chan struct{})
+		b.activeStdin = reader
+	} else {
+		b.command.Stdin = stdin
+	}
+
+	if err := b.command.Start(); err != nil {
+		return err
+	}
+	b.running = true
+	trackProcess(b)
+
+	if stdinPipe != nil {
+		go func() {
+			io.Copy(stdinPipe, reader)
+			stdinPipe.Close()
+		}()
+	}
+	return nil
+}
+
+func (b *BashProcess) wait() error {
+	err := b.command.Wait()
+	for _, f := range b.afterWait {
+		f()
+	}
+	b.usage = newResourceUsage(b.command.ProcessState, time.Since(b.startTime))
+
+	untrackProcess(b)
+	b.mu.Lock()
+	b.running = false
+	b.closeStdin()
+	b.mu.Unlock()
+	return err
+}
+
+// CloseStdin signals EOF to the process once the lines that were already sent with Stdin
+// have been read. Only has an effect when a custom stdin is used.
+func (b *BashProcess) CloseStdin() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closeStdin()
+}
+
+func (b *BashProcess) closeStdin() {
+	if b.activeStdin != nil {
+		close(b.activeStdin.done)
+		b.activeStdin = nil
+	}
+}
+
+// kill stops the process if it is running
+func (b *BashProcess) kill() error {
+	return b.signal(os.Kill)
+}
+
+// signal sends sig to the process if it is running. Processes that have their own process
+// group receive it along with every process they started.
+func (b *BashProcess) signal(sig os.Signal) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.running || b.command.Process == nil {
+		return nil
+	}
+	return signalProcess(b.command.Process, b.ownGroup, sig)
+}
+
+func isTerminal(r io.Reader) bool {
+	file, ok := r.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
 }
 
 func Bash() *BashProcess {
@@ -160,6 +365,20 @@ func Bash() *BashProcess {
 	}
 }
 
+// exitCode returns the exit code of a finished process given the error returned by
+// Exec. Returns -1 if the process did not exit normally (e.g. it was killed by a signal)
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
 func Sep() string {
 	if runtime.GOOS == "windows" {
 		return "\r\n"
@@ -168,17 +387,19 @@ func Sep() string {
 	}
 }
 
+// ReadHidden reads a line from the terminal without echoing it, even when stdin is piped.
+// Ctrl-C returns ErrInterrupted and leaves the terminal as it was.
 func ReadHidden(prompt string) (string, error) {
-	fmt.Print(prompt)
-	p, err := terminal.ReadPassword(0)
-	fmt.Println()
-	return string(p), err
+	return readHidden(prompt, false)
 }
 
 func ReadInput(prompt string) (string, error) {
+	if answer, ok, err := scriptedAnswer(promptKey(prompt), prompt, false); ok {
+		return answer, err
+	}
+
 	fmt.Print(prompt)
-	reader := bufio.NewReader(os.Stdin)
-	line, err := reader.ReadString('\n')
+	line, err := bufferedStdin().ReadString('\n')
 	line = strings.TrimSuffix(line, "\n")
 	return line, err
 }
@@ -0,0 +1,76 @@
+package gocli
+
+import (
+	"reflect"
+)
+
+// Built-in options are added by the CLI to every command, like "--help". They are shown
+// in the help text, but not passed to Context.GetOptions. Options defined by a command
+// take precedence over built-in options with the same name.
+const (
+	watchOption      = "watch"
+	watchClearOption = "watch-clear"
+)
+
+func (cli *cli) addBuiltin(long, short string, kind reflect.Kind, description string) {
+	for _, builtin := range cli.builtins {
+		if builtin.long == long {
+			return
+		}
+	}
+
+	value, _ := cast(reflect.Zero(kindTypes[kind]).Interface(), kind)
+	cli.builtins = append(cli.builtins, option{
+		long:        long,
+		short:       short,
+		kind:        kind,
+		value:       value,
+		description: description,
+	})
+}
+
+var kindTypes = map[reflect.Kind]reflect.Type{
+	reflect.String: reflect.TypeOf(""),
+	reflect.Bool:   reflect.TypeOf(false),
+	reflect.Int:    reflect.TypeOf(0),
+}
+
+// newBuiltins returns new copies of the built-in options, for a single execution
+func (cli *cli) newBuiltins() map[string]*option {
+	builtins := map[string]*option{}
+	for _, builtin := range cli.builtins {
+		opt := builtin
+		builtins[opt.long] = &opt
+	}
+	return builtins
+}
+
+func addBuiltinOptions(optionsMap map[string]*option, builtins map[string]*option) {
+	for _, builtin := range builtins {
+		if _, exists := optionsMap[builtin.long]; exists {
+			continue
+		}
+		optionsMap[builtin.long] = builtin
+		if _, exists := optionsMap[builtin.short]; builtin.short != "" && !exists {
+			optionsMap[builtin.short] = builtin
+		}
+	}
+}
+
+func removeBuiltinOptions(options []*option, builtins map[string]*option) []*option {
+	result := []*option{}
+	for _, opt := range options {
+		if builtins[opt.long] != opt {
+			result = append(result, opt)
+		}
+	}
+	return result
+}
+
+// builtinValue returns the value of a built-in option, or nil if it is not enabled
+func (ctx *Context) builtinValue(long string) interface{} {
+	if builtin, ok := ctx.builtins[long]; ok {
+		return builtin.value
+	}
+	return nil
+}
@@ -3,11 +3,17 @@ package gocli
 import (
 	"fmt"
 	"os"
+	"time"
 )
 
 type cli struct {
-	nodes map[*Command]*commandNode
-	root  *commandNode
+	nodes           map[*Command]*commandNode
+	root            *commandNode
+	shutdownTimeout time.Duration
+	shutdownSignals bool
+	jobs            bool
+	builtins        []option
+	promptMissing   bool
 }
 
 func NewCli(root *Command) *cli {
@@ -17,11 +23,27 @@ func NewCli(root *Command) *cli {
 
 	rootNode := newCommandNode(nil, root)
 	return &cli{
-		nodes: map[*Command]*commandNode{root: rootNode},
-		root:  rootNode,
+		nodes:           map[*Command]*commandNode{root: rootNode},
+		root:            rootNode,
+		shutdownTimeout: DefaultShutdownTimeout,
 	}
 }
 
+// EnableGracefulShutdown makes the CLI handle SIGINT and SIGTERM: Context.Context is
+// cancelled, the signal is forwarded to the child processes, and the CLI exits once the
+// command and the cleanup hooks have finished, or after the shutdown timeout. A second
+// signal exits immediately. Without it, signals exit the CLI immediately, and the cleanup
+// hooks only run when the command returns or fails.
+func (cli *cli) EnableGracefulShutdown() {
+	cli.shutdownSignals = true
+}
+
+// SetShutdownTimeout sets how long the CLI waits for the command and the cleanup hooks to
+// finish after a SIGINT or SIGTERM, before it exits anyway. See EnableGracefulShutdown.
+func (cli *cli) SetShutdownTimeout(timeout time.Duration) {
+	cli.shutdownTimeout = timeout
+}
+
 func (cli *cli) AddChild(p, c *Command) {
 	if err := validateCommand(p); err != nil {
 		fatal(err)
@@ -63,8 +85,15 @@ func (c *cli) Exec() {
 		args[i], args[len(args)-1] = args[len(args)-1], args[i]
 	}
 
+	if c.jobs {
+		refreshJobs()
+	}
+
 	node := c.root
-	ctx := &Context{}
+	ctx := &Context{shutdown: newShutdown(c.shutdownTimeout), builtins: c.newBuiltins(), promptMissing: c.promptMissing}
+	ctx.shutdown.start(c.shutdownSignals)
+	defer ctx.shutdown.finish()
+
 	for {
 		if node.value.Middleware != nil && len(node.value.Middleware) > 0 {
 			for _, middleware := range node.value.Middleware {
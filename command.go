@@ -49,6 +49,11 @@ func (c *Command) exec(args []string, ctx *Context) {
 	// build the context
 	buildContext(c, args, ctx)
 
+	if pattern, _ := ctx.builtinValue(watchOption).(string); pattern != "" && !isWatchRun() {
+		watch(ctx, pattern, ctx.builtinValue(watchClearOption) == true)
+		return
+	}
+
 	c.Behavior(ctx)
 }
 
@@ -1,12 +1,14 @@
 package gocli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 )
 
 type Context struct {
@@ -18,6 +20,20 @@ type Context struct {
 
 	commandStr string
 
+	newRunner func() Runner
+
+	shutdown *shutdown
+
+	builtins map[string]*option
+
+	// prompt for missing required values
+	promptMissing bool
+
+	// format of Context.Print
+	outputFormat   string
+	outputTemplate *template.Template
+	outputJSONPath *jsonPath
+
 	Value interface{}
 }
 
@@ -26,6 +42,39 @@ func (ctx *Context) GetHelpStr() string {
 	return ctx.helpStr
 }
 
+// Runner returns a new Runner to execute shell commands with. Defaults to Bash(), unless
+// it was replaced with SetRunner.
+func (ctx *Context) Runner() Runner {
+	if ctx.newRunner == nil {
+		return Bash()
+	}
+	return ctx.newRunner()
+}
+
+// SetRunner replaces the Runner returned by Context.Runner, e.g. with FakeRunner.New in tests.
+func (ctx *Context) SetRunner(newRunner func() Runner) {
+	ctx.newRunner = newRunner
+}
+
+// Context returns a context.Context that is cancelled once the command has returned, or when
+// the CLI receives SIGINT or SIGTERM with EnableGracefulShutdown.
+func (ctx *Context) Context() context.Context {
+	if ctx.shutdown == nil {
+		return context.Background()
+	}
+	return ctx.shutdown.ctx
+}
+
+// OnCleanup registers a hook that runs before the CLI exits, both when the command returns
+// or fails, and when the CLI is interrupted with EnableGracefulShutdown. Hooks run in the
+// reverse order they were registered.
+func (ctx *Context) OnCleanup(hook func()) {
+	if ctx.shutdown == nil {
+		ctx.shutdown = newShutdown(DefaultShutdownTimeout)
+	}
+	ctx.shutdown.onCleanup(hook)
+}
+
 // GetRawArgs returns the string CLI arguments that are passed by the user. i.e. "$@" in bash terms
 func (ctx *Context) GetRawArgs() []string {
 	return os.Args
@@ -56,6 +105,20 @@ func (ctx *Context) GetOptions(opt interface{}) {
 	populateInterface(optionsMap, opt)
 }
 
+// Cmd renders a command template (see the package level Cmd) with the CLI options and
+// arguments of the current command. Fields are referenced by their Go field names,
+// e.g. `{{.Branch}}`, and every interpolated value is shell quoted.
+func (ctx *Context) Cmd(tmpl string) (string, error) {
+	data := map[string]interface{}{}
+	for _, option := range ctx.options {
+		data[convertToCamelCase(option.long)] = option.value
+	}
+	for _, argument := range ctx.arguments {
+		data[convertToCamelCase(argument.name)] = argument.value
+	}
+	return Cmd(tmpl, data)
+}
+
 func populateInterface(m map[string]interface{}, i interface{}) {
 	iValue := reflect.ValueOf(i).Elem()
 	iType := iValue.Type()
@@ -120,6 +183,10 @@ func populateArgumentsAndOptions(args []string, optionsMap map[string]*option, a
 		idx++
 	}
 
+	return nil
+}
+
+func checkRequired(optionsMap map[string]*option, arguments []*argument) error {
 	// check for missing arguments
 	for _, argument := range arguments {
 		if argument.required && !argument.populated {
@@ -140,6 +207,7 @@ func populateArgumentsAndOptions(args []string, optionsMap map[string]*option, a
 func buildContext(c *Command, args []string, ctx *Context) {
 	ctx.commandStr = c.fullName()
 	optionsMap := buildOptionsMap(c)
+	addBuiltinOptions(optionsMap, ctx.builtins)
 	ctx.arguments = buildArguments(c)
 	ctx.helpStr = getHelpStr(optionsMap, ctx.arguments, c)
 
@@ -149,11 +217,24 @@ func buildContext(c *Command, args []string, ctx *Context) {
 	}
 
 	err := populateArgumentsAndOptions(args, optionsMap, ctx.arguments)
+	// configured even if parsing failed, so that the error is printed in the right format
+	if outputErr := ctx.configureOutput(); err == nil {
+		err = outputErr
+	}
+	if err == nil {
+		err = ctx.configureAnswers()
+	}
+	if err == nil && ctx.promptMissing && canPrompt() {
+		err = promptForMissing(optionsMap, ctx.arguments)
+	}
+	if err == nil {
+		err = checkRequired(optionsMap, ctx.arguments)
+	}
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fatal(err)
 	}
 	ctx.options = optionsMapToArray(optionsMap)
+	ctx.options = removeBuiltinOptions(ctx.options, ctx.builtins)
 }
 
 func hasHelp(args []string) (int, bool) {
@@ -176,7 +257,7 @@ func (b Bylong) Less(i, j int) bool { return b[i].long < b[j].long }
 
 type Byfirst [][]string
 
-func (b Byfirst) Len() int           { return len(b[0]) }
+func (b Byfirst) Len() int           { return len(b) }
 func (b Byfirst) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
 func (b Byfirst) Less(i, j int) bool { return b[i][0] < b[j][0] }
 
@@ -360,7 +441,15 @@ func optionTypeHelp(v valued) string {
 func cast(v interface{}, kind reflect.Kind) (interface{}, error) {
 	switch kind {
 	case reflect.Bool:
-		return v.(bool), nil
+		b, ok := v.(bool)
+		if ok {
+			return b, nil
+		}
+		b, err := strconv.ParseBool(v.(string))
+		if err != nil {
+			return nil, fmt.Errorf("Expected a boolean, got '%s'", v)
+		}
+		return b, nil
 	case reflect.String:
 		return v.(string), nil
 	case reflect.Int:
@@ -430,7 +519,7 @@ func cast(v interface{}, kind reflect.Kind) (interface{}, error) {
 			return f, nil
 		}
 
-		fl, err := strconv.ParseFloat(v.(string), 32)
+		fl, err := strconv.ParseFloat(v.(string), 64)
 		if err != nil {
 			return nil, fmt.Errorf("Expected a float, got '%s'", v)
 		}
@@ -0,0 +1,33 @@
+package gocli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCastFloats(t *testing.T) {
+	tests := []struct {
+		value string
+		kind  reflect.Kind
+		want  interface{}
+	}{
+		{"0.1", reflect.Float64, 0.1},
+		{"1e300", reflect.Float64, 1e300},
+		{"0.1", reflect.Float32, float32(0.1)},
+		{"2.5", reflect.Float32, float32(2.5)},
+	}
+
+	for _, tt := range tests {
+		got, err := cast(tt.value, tt.kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("cast(%q, %s) = %v, want %v", tt.value, tt.kind, got, tt.want)
+		}
+	}
+
+	if _, err := cast("abc", reflect.Float64); err == nil {
+		t.Error("cast(\"abc\", float64) succeeded, want error")
+	}
+}
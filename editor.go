@@ -0,0 +1,101 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadEditor opens the editor of the user ($VISUAL, then $EDITOR, then vi) on a temporary
+// file that contains template, and returns the saved text once the editor exits. Lines that
+// start with "#" are comments: they can be used in template for instructions, and are
+// removed from the result. An empty result is an error, so that the user can abort by
+// deleting everything. Its prompt key is "editor".
+func ReadEditor(template string) (string, error) {
+	if answer, ok, err := scriptedAnswer(editorKey, "", false); ok {
+		if err == nil && stripComments(answer) == "" {
+			err = fmt.Errorf("Aborting because the text is empty")
+		}
+		return stripComments(answer), err
+	}
+
+	file, err := os.CreateTemp("", filepath.Base(os.Args[0])+"-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("Could not create a temporary file for the editor. %s", err.Error())
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(template)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("Could not write the temporary file '%s'. %s", file.Name(), err.Error())
+	}
+
+	if err := runEditor(file.Name()); err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("Could not read the temporary file '%s'. %s", file.Name(), err.Error())
+	}
+
+	text := stripComments(string(content))
+	if text == "" {
+		return "", fmt.Errorf("Aborting because the text is empty")
+	}
+	return text, nil
+}
+
+const editorKey = "editor"
+
+func editorCommand() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(env)); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// runEditor runs the editor on path, connected to the terminal even if stdin or stdout
+// are redirected
+func runEditor(path string) error {
+	editor := editorCommand()
+	cmd := editor + " " + ShellQuote(path)
+
+	b := Bash()
+	var err error
+	if isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+		err = b.start(cmd, os.Stdin, os.Stdout, os.Stderr)
+	} else {
+		tty, ttyErr := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if ttyErr != nil {
+			return fmt.Errorf("Could not open the editor '%s'. No terminal is available", editor)
+		}
+		defer tty.Close()
+		err = b.start(cmd, tty, tty, tty)
+	}
+	if err == nil {
+		err = b.wait()
+	}
+
+	if err != nil {
+		return fmt.Errorf("The editor '%s' failed. %s", editor, err.Error())
+	}
+	return nil
+}
+
+// stripComments removes the lines that start with "#", and the surrounding blank lines
+func stripComments(text string) string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
@@ -14,6 +14,12 @@ func newError(msg string, a ...any) error {
 }
 
 func fatal(err error) {
-	fmt.Println(err)
-	os.Exit(1)
+	printError(err)
+	exit(1)
+}
+
+// exit runs the cleanup hooks and exits with code
+func exit(code int) {
+	runCleanup()
+	os.Exit(code)
 }
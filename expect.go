@@ -0,0 +1,150 @@
+package gocli
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// expectBuffer collects the stdout of a spawned process until it is consumed by Expect.
+type expectBuffer struct {
+	mu      sync.Mutex
+	buf     []byte
+	changed chan struct{}
+	handler StdHandler
+	exited  bool
+	err     error
+}
+
+func newExpectBuffer(handler StdHandler) *expectBuffer {
+	return &expectBuffer{changed: make(chan struct{}), handler: handler}
+}
+
+func (e *expectBuffer) Write(p []byte) (int, error) {
+	if e.handler != nil {
+		if err := e.handler(p); err != nil {
+			return 0, err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.buf = append(e.buf, p...)
+	e.notify()
+	return len(p), nil
+}
+
+// notify wakes up everyone waiting for a change. Must be called while holding the lock
+func (e *expectBuffer) notify() {
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+func (e *expectBuffer) exit(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.exited = true
+	e.err = err
+	e.notify()
+}
+
+// Spawn starts cmd without waiting for it to exit, so that it can be driven with Expect,
+// Send and ExpectEOF. The stdout of the process is buffered for Expect and is also passed
+// to the stdout handler, if one is defined. A custom stdin is used if none is defined.
+func (b *BashProcess) Spawn(cmd string) error {
+	if b.stdinReader == nil {
+		b.CustomStdin(nil)
+	}
+
+	expect := newExpectBuffer(b.stdoutHandler)
+	if err := b.start(cmd, b.stdinReader, expect, b.stderr()); err != nil {
+		return err
+	}
+	b.expect = expect
+
+	go func() {
+		expect.exit(b.wait())
+	}()
+	return nil
+}
+
+// Expect waits until the unread stdout of a spawned process matches re, and returns the
+// match followed by its submatches. The output up to the end of the match is consumed.
+func (b *BashProcess) Expect(re *regexp.Regexp, timeout time.Duration) ([]string, error) {
+	expect := b.expect
+	if expect == nil {
+		return nil, fmt.Errorf("Cannot expect '%s'. The process was not started with Spawn", re)
+	}
+
+	deadline := time.After(timeout)
+	for {
+		expect.mu.Lock()
+		if loc := re.FindSubmatchIndex(expect.buf); loc != nil {
+			matches := make([]string, len(loc)/2)
+			for i := range matches {
+				if loc[2*i] >= 0 {
+					matches[i] = string(expect.buf[loc[2*i]:loc[2*i+1]])
+				}
+			}
+			expect.buf = expect.buf[loc[1]:]
+			expect.mu.Unlock()
+			return matches, nil
+		}
+		if expect.exited {
+			unread := string(expect.buf)
+			expect.mu.Unlock()
+			return nil, fmt.Errorf("Process exited before output matched '%s'. Unread output: %q", re, unread)
+		}
+		changed := expect.changed
+		expect.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline:
+			expect.mu.Lock()
+			unread := string(expect.buf)
+			expect.mu.Unlock()
+			return nil, fmt.Errorf("Timed out after %s waiting for output to match '%s'. Unread output: %q", timeout, re, unread)
+		}
+	}
+}
+
+// ExpectEOF waits until a spawned process exits and returns its exit error.
+func (b *BashProcess) ExpectEOF(timeout time.Duration) error {
+	expect := b.expect
+	if expect == nil {
+		return fmt.Errorf("Cannot expect EOF. The process was not started with Spawn")
+	}
+
+	deadline := time.After(timeout)
+	for {
+		expect.mu.Lock()
+		if expect.exited {
+			err := expect.err
+			expect.mu.Unlock()
+			return err
+		}
+		changed := expect.changed
+		expect.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return fmt.Errorf("Timed out after %s waiting for the process to exit", timeout)
+		}
+	}
+}
+
+// Send writes s to the stdin of a running process. No newline is appended.
+func (b *BashProcess) Send(s string) error {
+	b.mu.Lock()
+	running := b.running && b.activeStdin != nil
+	b.mu.Unlock()
+
+	if !running {
+		return fmt.Errorf("Cannot send input. The process is not running or its stdin is closed")
+	}
+	b.Stdin(s)
+	return nil
+}
@@ -0,0 +1,204 @@
+package gocli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FormValidator is implemented by form structs that validate the answers as a whole.
+type FormValidator interface {
+	Validate() error
+}
+
+// Form asks for every exported field of the struct v points to, one step at a time, and
+// then shows the answers for review before they are written back into the struct.
+// Fields are described with the same tags as options:
+//
+//	type Setup struct {
+//		Name     string `required:"true" description:"Project name"`
+//		Region   string `enum:"eu-west-1,us-east-1" description:"Region"`
+//		Replicas int    `description:"Number of replicas"`
+//		Token    string `secret:"true" description:"API token"`
+//	}
+//
+// The current values of the fields are used as defaults. If *v implements FormValidator,
+// the answers are only accepted once Validate returns nil. The prompt key of a field is
+// its name in the case of options, e.g. "replicas".
+func Form(v interface{}) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Form expects a pointer to a struct, got %T", v)
+	}
+
+	// answers are written into a copy, so that the struct is unchanged if the form fails
+	answers := reflect.New(ptr.Elem().Type())
+	answers.Elem().Set(ptr.Elem())
+
+	fields, err := formFields(answers.Elem())
+	if err != nil {
+		return err
+	}
+
+	for i, field := range fields {
+		if err := field.ask(fmt.Sprintf("[%d/%d] %s", i+1, len(fields), field.label)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		fmt.Println()
+		fmt.Println(reviewForm(fields))
+
+		ok, err := Confirm("Are these answers correct?", true)
+		if err != nil {
+			return err
+		}
+		if ok {
+			validator, isValidator := answers.Interface().(FormValidator)
+			if !isValidator {
+				break
+			}
+			err := validator.Validate()
+			if err == nil {
+				break
+			}
+			if isNonInteractive() {
+				return err
+			}
+			fmt.Println(Red("%s", err.Error()))
+		}
+
+		labels := make([]string, len(fields))
+		for i, field := range fields {
+			labels[i] = field.label
+		}
+		i, err := Select("Which answer do you want to change?", labels)
+		if err != nil {
+			return err
+		}
+		if err := fields[i].ask(fields[i].label); err != nil {
+			return err
+		}
+	}
+
+	ptr.Elem().Set(answers.Elem())
+	return nil
+}
+
+type formField struct {
+	name     string
+	label    string
+	required bool
+	secret   bool
+	choices  []string
+	value    reflect.Value
+}
+
+func formFields(v reflect.Value) ([]*formField, error) {
+	fields := []*formField{}
+	for idx := 0; idx < v.NumField(); idx++ {
+		fType := v.Type().Field(idx)
+		if !v.Field(idx).CanSet() {
+			continue
+		}
+
+		kind := fType.Type.Kind()
+		if kind != reflect.String && kind != reflect.Bool && !isIntKind(kind) && !isFloatKind(kind) {
+			return nil, fmt.Errorf("Invalid type for form field '%s'. Allowed types are string, bool, ints, floats and time.Duration", fType.Name)
+		}
+
+		field := &formField{
+			name:  convertToJSONCase(fType.Name),
+			label: convertToJSONCase(fType.Name),
+			value: v.Field(idx),
+		}
+		if description, exists := fType.Tag.Lookup("description"); exists && description != "" {
+			field.label = description
+		}
+		if required, exists := fType.Tag.Lookup("required"); exists && required == "true" {
+			field.required = true
+		}
+		if secret, exists := fType.Tag.Lookup("secret"); exists && secret == "true" {
+			field.secret = true
+		}
+		if enum, exists := fType.Tag.Lookup("enum"); exists {
+			for _, choice := range strings.Split(enum, ",") {
+				if choice = strings.TrimSpace(choice); choice != "" {
+					field.choices = append(field.choices, choice)
+				}
+			}
+			for _, choice := range field.choices {
+				if _, err := castType(choice, fType.Type); err != nil {
+					return nil, fmt.Errorf("Invalid enum value for form field '%s'. %s", fType.Name, err.Error())
+				}
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// ask prompts for the value of the field, using its current value as the default
+func (f *formField) ask(label string) error {
+	current := f.value.Interface()
+
+	if len(f.choices) > 0 {
+		initial := 0
+		for i, choice := range f.choices {
+			if choice == fmt.Sprint(current) {
+				initial = i
+			}
+		}
+		i, err := selectChoice(f.name, label, f.choices, initial)
+		if err != nil {
+			return err
+		}
+		value, _ := castType(f.choices[i], f.value.Type())
+		f.value.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	if f.value.Kind() == reflect.Bool {
+		answer, err := confirm(f.name, label, f.value.Bool())
+		if err != nil {
+			return err
+		}
+		f.value.SetBool(answer)
+		return nil
+	}
+
+	var value interface{}
+	prompt := NewPrompt[string](label).Key(f.name).Validate(func(s string) (err error) {
+		value, err = castType(s, f.value.Type())
+		return err
+	})
+	if !f.value.IsZero() || !f.required {
+		prompt.Default(fmt.Sprint(current))
+	}
+	if f.secret {
+		prompt.Hidden()
+	}
+	if _, err := prompt.Ask(); err != nil {
+		return err
+	}
+	f.value.Set(reflect.ValueOf(value))
+	return nil
+}
+
+func reviewForm(fields []*formField) string {
+	width := 0
+	for _, field := range fields {
+		width = max(width, len(field.label))
+	}
+
+	txt := "Review your answers:" + Sep()
+	for _, field := range fields {
+		value := fmt.Sprint(field.value.Interface())
+		if field.secret && value != "" {
+			value = "********"
+		}
+		txt += "  " + paddedName(field.label, width+2) + Cyan("%s", value) + Sep()
+	}
+	return strings.TrimSuffix(txt, Sep())
+}
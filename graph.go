@@ -0,0 +1,406 @@
+package gocli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GraphTask is a task of a TaskGraph.
+type GraphTask struct {
+	Name string
+
+	// Command template, rendered with the options and arguments of the command (see Context.Cmd)
+	Cmd string
+
+	// Names of the tasks that have to run first
+	Deps []string
+
+	// File globs whose contents are inputs of the task. "**" matches any number of directories
+	Inputs []string
+
+	// Long names of the options whose values are inputs of the task
+	Values []string
+
+	// File globs that the task produces. The task runs again if one of them is missing
+	Outputs []string
+}
+
+// GraphOptions configure TaskGraph.Run.
+type GraphOptions struct {
+	// Print the plan without running anything
+	DryRun bool
+
+	// Ignore the cache and run every task
+	Force bool
+
+	// Maximum number of tasks running at the same time. Zero means no limit
+	Concurrency int
+}
+
+// TaskGraph runs tasks in dependency order. Tasks that don't depend on each other run in
+// parallel, and tasks whose inputs didn't change since their last successful run are skipped.
+type TaskGraph struct {
+	tasks map[string]*GraphTask
+
+	// File where the input hashes of the last successful runs are stored. Defaults to
+	// .gocli-cache.json in the working directory
+	CacheFile string
+}
+
+func NewTaskGraph() *TaskGraph {
+	return &TaskGraph{tasks: map[string]*GraphTask{}, CacheFile: ".gocli-cache.json"}
+}
+
+// Add adds a task to the graph.
+func (g *TaskGraph) Add(task GraphTask) *TaskGraph {
+	if task.Name == "" {
+		fatal(newSetupError("Received GraphTask definition with an empty name"))
+	}
+	if _, exists := g.tasks[task.Name]; exists {
+		fatal(newSetupError("Task '%s' is defined twice", task.Name))
+	}
+	g.tasks[task.Name] = &task
+	return g
+}
+
+// Command returns a command that runs the graph. It runs every task, or the task given as
+// argument along with its dependencies, and has the options --dry-run, --force and --jobs.
+func (g *TaskGraph) Command(name string) *Command {
+	return &Command{
+		Name:      name,
+		ShortDesc: "Run tasks",
+		LongDesc:  "Run tasks in dependency order, skipping the tasks whose inputs did not change",
+		Options: struct {
+			DryRun bool `description:"Print the plan without running anything"`
+			Force  bool `description:"Ignore the cache and run every task"`
+			Jobs   int  `short:"j" description:"Maximum number of tasks running at the same time"`
+		}{},
+		Arguments: struct {
+			Task string `description:"Task to run along with its dependencies. Defaults to every task"`
+		}{},
+		Behavior: func(ctx *Context) {
+			var opts struct {
+				DryRun bool
+				Force  bool
+				Jobs   int
+			}
+			var args struct{ Task string }
+			ctx.GetOptions(&opts)
+			ctx.GetArguments(&args)
+
+			targets := []string{}
+			if args.Task != "" {
+				targets = append(targets, args.Task)
+			}
+			if err := g.Run(ctx, targets, GraphOptions{DryRun: opts.DryRun, Force: opts.Force, Concurrency: opts.Jobs}); err != nil {
+				fatal(newError("%s", err.Error()))
+			}
+		},
+	}
+}
+
+// Run runs the targets and their dependencies, or every task if no targets are given.
+func (g *TaskGraph) Run(ctx *Context, targets []string, opts GraphOptions) error {
+	waves, err := g.plan(targets)
+	if err != nil {
+		return err
+	}
+
+	cache := g.loadCache()
+	for i, wave := range waves {
+		jobs := []ParallelJob{}
+		hashes := map[string]string{}
+		for _, task := range wave {
+			cmd, err := ctx.Cmd(task.Cmd)
+			if err != nil {
+				return fmt.Errorf("Task '%s'. %s", task.Name, err.Error())
+			}
+
+			hash, err := task.hash(ctx, cmd)
+			if err != nil {
+				return err
+			}
+
+			upToDate := !opts.Force && cache[task.Name] == hash && task.hasOutputs()
+			if opts.DryRun {
+				status := Green("run")
+				if upToDate {
+					status = Yellow("skip (up to date)")
+				}
+				fmt.Printf("%d. %s: %s%s", i+1, Cyan("%s", task.Name), status, Sep())
+				continue
+			}
+			if upToDate {
+				fmt.Println(Yellow("%s is up to date", task.Name))
+				continue
+			}
+
+			hashes[task.Name] = hash
+			jobs = append(jobs, ParallelJob{Name: task.Name, Cmd: cmd})
+		}
+
+		if len(jobs) == 0 {
+			continue
+		}
+
+		report := runGraphJobs(ctx, jobs, opts.Concurrency)
+		for _, result := range report.Results {
+			if result.Err == nil {
+				cache[result.Name] = hashes[result.Name]
+			}
+		}
+		if err := g.saveCache(cache); err != nil {
+			return err
+		}
+		if err := report.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runGraphJobs runs the jobs with the runners of ctx. Runners other than BashProcess, e.g.
+// FakeRunner or Replayer, run the jobs one at a time in order, so that their invocations
+// are deterministic
+func runGraphJobs(ctx *Context, jobs []ParallelJob, concurrency int) *ParallelReport {
+	runners := make([]Runner, len(jobs))
+	parallel := true
+	for i := range jobs {
+		runners[i] = ctx.Runner()
+		if process, ok := runners[i].(*BashProcess); ok {
+			jobs[i].Process = process
+		} else {
+			parallel = false
+		}
+	}
+	if parallel {
+		return RunParallel(jobs, ParallelOptions{Concurrency: concurrency, FailFast: true})
+	}
+
+	width := 0
+	for _, job := range jobs {
+		width = max(len(job.Name), width)
+	}
+
+	var mu sync.Mutex
+	report := &ParallelReport{Results: make([]JobResult, len(jobs))}
+	failed := false
+	for i, job := range jobs {
+		report.Results[i] = JobResult{Name: job.Name, ExitCode: -1}
+		if failed {
+			report.Results[i].Skipped = true
+			report.Results[i].Err = fmt.Errorf("Job '%s' was skipped", job.Name)
+			continue
+		}
+
+		prefix := prefixColors[i%len(prefixColors)]("%s", paddedName(job.Name, width)) + " | "
+		stdout := newPrefixWriter(prefix, os.Stdout, &mu, nil)
+		stderr := newPrefixWriter(prefix, os.Stderr, &mu, nil)
+		runners[i].HandleStdout(func(chunk []byte) error {
+			_, err := stdout.Write(chunk)
+			return err
+		})
+		runners[i].HandleStderr(func(chunk []byte) error {
+			_, err := stderr.Write(chunk)
+			return err
+		})
+
+		start := time.Now()
+		err := runners[i].Exec(job.Cmd)
+		stdout.Flush()
+		stderr.Flush()
+
+		report.Results[i].Duration = time.Since(start)
+		report.Results[i].ExitCode = exitCode(err)
+		report.Results[i].Err = err
+		failed = err != nil
+	}
+	return report
+}
+
+// plan sorts the tasks topologically. Each wave only depends on the previous waves, so
+// the tasks of a wave can run in parallel
+func (g *TaskGraph) plan(targets []string) ([][]*GraphTask, error) {
+	selected := map[string]bool{}
+	var selectTask func(name string, chain []string) error
+	selectTask = func(name string, chain []string) error {
+		task, exists := g.tasks[name]
+		if !exists {
+			return fmt.Errorf("Task '%s' does not exist", name)
+		}
+		for _, previous := range chain {
+			if previous == name {
+				return fmt.Errorf("Tasks have a circular dependency: %s -> %s", strings.Join(chain, " -> "), name)
+			}
+		}
+		if selected[name] {
+			return nil
+		}
+		for _, dep := range task.Deps {
+			if err := selectTask(dep, append(chain, name)); err != nil {
+				return err
+			}
+		}
+		selected[name] = true
+		return nil
+	}
+
+	if len(targets) == 0 {
+		for name := range g.tasks {
+			targets = append(targets, name)
+		}
+	}
+	for _, target := range targets {
+		if err := selectTask(target, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	waves := [][]*GraphTask{}
+	done := map[string]bool{}
+	for len(done) < len(selected) {
+		wave := []*GraphTask{}
+		for name := range selected {
+			if done[name] {
+				continue
+			}
+			ready := true
+			for _, dep := range g.tasks[name].Deps {
+				ready = ready && done[dep]
+			}
+			if ready {
+				wave = append(wave, g.tasks[name])
+			}
+		}
+		sort.Slice(wave, func(i, j int) bool { return wave[i].Name < wave[j].Name })
+		for _, task := range wave {
+			done[task.Name] = true
+		}
+		waves = append(waves, wave)
+	}
+	return waves, nil
+}
+
+// hash returns the hash of the command, the option values and the input files of the task
+func (t *GraphTask) hash(ctx *Context, cmd string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "cmd:%s\n", cmd)
+
+	values := map[string]interface{}{}
+	for _, option := range ctx.options {
+		values[option.long] = option.value
+	}
+	for _, name := range t.Values {
+		fmt.Fprintf(h, "value:%s=%v\n", name, values[name])
+	}
+
+	files, err := globFiles(t.Inputs)
+	if err != nil {
+		return "", fmt.Errorf("Task '%s'. %s", t.Name, err.Error())
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", fmt.Errorf("Task '%s'. Could not read input '%s'. %s", t.Name, file, err.Error())
+		}
+		fmt.Fprintf(h, "file:%s\n", file)
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("Task '%s'. Could not read input '%s'. %s", t.Name, file, err.Error())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (t *GraphTask) hasOutputs() bool {
+	for _, pattern := range t.Outputs {
+		files, err := globFiles([]string{pattern})
+		if err != nil || len(files) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// globFiles returns the sorted regular files that match any of the patterns
+func globFiles(patterns []string) ([]string, error) {
+	found := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+				found[match] = true
+			}
+		}
+	}
+
+	files := make([]string, 0, len(found))
+	for file := range found {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// glob extends filepath.Glob with "**", which matches any number of directories
+func glob(pattern string) ([]string, error) {
+	pattern = filepath.Clean(pattern)
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	root, rest, _ := strings.Cut(pattern, "**")
+	root = filepath.Clean(root + ".")
+	rest = strings.TrimPrefix(rest, string(filepath.Separator))
+	if rest == "" {
+		// a trailing "**" matches everything below root
+		rest = "*"
+	}
+	// like filepath.Glob, a missing directory has no matches
+	if _, err := os.Lstat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	matches := []string{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+		dirMatches, err := glob(filepath.Join(path, rest))
+		matches = append(matches, dirMatches...)
+		return err
+	})
+	return matches, err
+}
+
+func (g *TaskGraph) loadCache() map[string]string {
+	cache := map[string]string{}
+	if data, err := os.ReadFile(g.CacheFile); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+func (g *TaskGraph) saveCache(cache map[string]string) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(g.CacheFile, data, 0644); err != nil {
+		return fmt.Errorf("Could not write task cache '%s'. %s", g.CacheFile, err.Error())
+	}
+	return nil
+}
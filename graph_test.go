@@ -0,0 +1,94 @@
+package gocli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGlobFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"a.txt", "src/b.txt", "src/c.go", "src/sub/d.txt", "src/sub/deep/e.go"} {
+		writeTestFile(t, filepath.Join(dir, file), file)
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.txt", []string{"a.txt"}},
+		{"src/*", []string{"src/b.txt", "src/c.go"}},
+		{"src/**", []string{"src/b.txt", "src/c.go", "src/sub/d.txt", "src/sub/deep/e.go"}},
+		{"src/**/", []string{"src/b.txt", "src/c.go", "src/sub/d.txt", "src/sub/deep/e.go"}},
+		{"src/**/*.go", []string{"src/c.go", "src/sub/deep/e.go"}},
+		{"**/*.txt", []string{"a.txt", "src/b.txt", "src/sub/d.txt"}},
+		{"missing/**", []string{}},
+		{"missing/**/*.go", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			files, err := globFiles([]string{filepath.Join(dir, tt.pattern)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, file := range files {
+				rel, _ := filepath.Rel(dir, file)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("globFiles(%q) = %v, want %v", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskGraphRunsAgainWhenAnInputChanges(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "src", "nested", "a.txt")
+	writeTestFile(t, input, "one")
+
+	output := filepath.Join(dir, "out")
+	writeTestFile(t, output, "")
+
+	g := NewTaskGraph().Add(GraphTask{
+		Name:    "build",
+		Cmd:     "make",
+		Inputs:  []string{filepath.Join(dir, "src", "**")},
+		Outputs: []string{output},
+	})
+	g.CacheFile = filepath.Join(dir, "cache.json")
+
+	fake := NewFakeRunner()
+	fake.On("make")
+	ctx := &Context{}
+	ctx.SetRunner(fake.New)
+
+	run := func(want int) {
+		t.Helper()
+		if err := g.Run(ctx, nil, GraphOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(fake.Invocations()); got != want {
+			t.Fatalf("task ran %d time(s), want %d", got, want)
+		}
+	}
+
+	run(1)
+	run(1)
+	writeTestFile(t, input, "two")
+	run(2)
+	run(2)
+}
@@ -0,0 +1,71 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+)
+
+// PromptForMissing makes the CLI prompt for required options and arguments that are
+// missing, instead of failing. The description of the field is used as the label, and
+// fields tagged `secret:"true"` are read without echoing them. The CLI only prompts when
+// it runs in a terminal outside of CI, otherwise missing values are still an error.
+func (cli *cli) PromptForMissing() {
+	cli.promptMissing = true
+}
+
+// canPrompt reports whether a user can answer prompts
+func canPrompt() bool {
+	return stdinIsTerminal() && os.Getenv("CI") == "" && !isNonInteractive()
+}
+
+func promptForMissing(optionsMap map[string]*option, arguments []*argument) error {
+	for _, argument := range arguments {
+		if !argument.required || argument.populated {
+			continue
+		}
+		value, err := promptValue(argument.name, argument.description, argument.kind, argument.secret)
+		if err != nil {
+			return err
+		}
+		argument.value = value
+		argument.populated = true
+	}
+
+	options := optionsMapToArray(optionsMap)
+	sort.Sort(Bylong(options))
+	for _, option := range options {
+		if !option.required || option.populated {
+			continue
+		}
+		value, err := promptValue(option.getName(), option.description, option.kind, option.secret)
+		if err != nil {
+			return err
+		}
+		option.value = value
+		option.populated = true
+	}
+	return nil
+}
+
+func promptValue(name, description string, kind reflect.Kind, secret bool) (interface{}, error) {
+	label := name
+	if description != "" {
+		label = fmt.Sprintf("%s (%s)", description, name)
+	}
+
+	var value interface{}
+	prompt := NewPrompt[string](label).Validate(func(s string) (err error) {
+		value, err = cast(s, kind)
+		return err
+	})
+	if secret {
+		prompt.Hidden()
+	}
+
+	if _, err := prompt.Ask(); err != nil {
+		return nil, fmt.Errorf("Could not read a value for '%s'. %s", name, err.Error())
+	}
+	return value, nil
+}
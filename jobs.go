@@ -0,0 +1,393 @@
+package gocli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	JobRunning = "running"
+	JobExited  = "exited"
+	JobStopped = "stopped"
+)
+
+// Job is a process that was started in the background with BashProcess.Detach. Its
+// metadata is persisted in the user state directory. ProcessStart is the start time of the
+// process, so that a later process that reuses its pid is not mistaken for the job.
+type Job struct {
+	ID           string    `json:"id"`
+	Pid          int       `json:"pid"`
+	ProcessStart uint64    `json:"processStart,omitempty"`
+	Command      string    `json:"command"`
+	StartTime    time.Time `json:"startTime"`
+	LogFile      string    `json:"logFile"`
+	Status       string    `json:"status"`
+	ExitCode     *int      `json:"exitCode,omitempty"`
+}
+
+// stateDir returns the directory where the CLI keeps its state, i.e.
+// $XDG_STATE_HOME/<cli name> or ~/.local/state/<cli name>
+func stateDir() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("Could not find the user state directory. %s", err.Error())
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, filepath.Base(os.Args[0])), nil
+}
+
+func jobsDir() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir = filepath.Join(dir, "jobs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("Could not create jobs directory '%s'. %s", dir, err.Error())
+	}
+	return dir, nil
+}
+
+// Detach starts cmd in the background and returns immediately. The process keeps running
+// after the CLI exits, and its stdout and stderr are written to the log file of the job.
+// Detached jobs are managed with the "jobs" command (see AddJobsCommand).
+func (b *BashProcess) Detach(cmd string) (*Job, error) {
+	dir, err := jobsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	job, metadata, err := newJob(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer metadata.Close()
+
+	log, err := os.Create(job.LogFile)
+	if err != nil {
+		os.Remove(metadata.Name())
+		return nil, fmt.Errorf("Could not create job log '%s'. %s", job.LogFile, err.Error())
+	}
+	defer log.Close()
+
+	command := b.bashCommand(cmd)
+	command.Stdout = log
+	command.Stderr = log
+	detachProcess(command)
+	if err := command.Start(); err != nil {
+		os.Remove(metadata.Name())
+		os.Remove(job.LogFile)
+		return nil, err
+	}
+
+	job.Pid = command.Process.Pid
+	job.ProcessStart, _ = processStartTime(job.Pid)
+	job.Command = cmd
+	job.StartTime = time.Now()
+	job.Status = JobRunning
+	if err := job.save(); err != nil {
+		return nil, err
+	}
+
+	// reap the process if it exits while the CLI is still running
+	go func() {
+		err := command.Wait()
+		if current, loadErr := loadJob(job.ID); loadErr == nil && current.Status == JobRunning {
+			code := exitCode(err)
+			current.Status = JobExited
+			current.ExitCode = &code
+			current.save()
+		}
+	}()
+	return job, nil
+}
+
+// newJob reserves the next free job ID by creating its metadata file
+func newJob(dir string) (*Job, *os.File, error) {
+	jobs, err := loadJobs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next := 1
+	for _, job := range jobs {
+		if id, err := strconv.Atoi(job.ID); err == nil && id >= next {
+			next = id + 1
+		}
+	}
+
+	for {
+		id := strconv.Itoa(next)
+		file, err := os.OpenFile(filepath.Join(dir, id+".json"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if os.IsExist(err) {
+			next++
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not create job. %s", err.Error())
+		}
+		return &Job{ID: id, LogFile: filepath.Join(dir, id+".log")}, file, nil
+	}
+}
+
+func (j *Job) save() error {
+	dir, err := jobsDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, j.ID+".json"), data, 0644)
+}
+
+// refresh marks the job as exited if its process is gone, or if its pid belongs to another
+// process
+func (j *Job) refresh() bool {
+	if j.Status == JobRunning && (j.Pid <= 0 || !processAlive(j.Pid, j.ProcessStart)) {
+		j.Status = JobExited
+		return true
+	}
+	return false
+}
+
+// Stop sends SIGTERM to the job and every process it started.
+func (j *Job) Stop() error {
+	if j.refresh() || j.Status != JobRunning {
+		j.save()
+		return fmt.Errorf("Job %s is not running", j.ID)
+	}
+
+	process, err := os.FindProcess(j.Pid)
+	if err != nil {
+		return err
+	}
+	if err := signalProcess(process, true, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("Could not stop job %s. %s", j.ID, err.Error())
+	}
+	j.Status = JobStopped
+	return j.save()
+}
+
+func loadJob(id string) (*Job, error) {
+	dir, err := jobsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("Job '%s' does not exist", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, fmt.Errorf("Could not parse job '%s'. %s", id, err.Error())
+	}
+	return job, nil
+}
+
+// loadJobs returns every persisted job, sorted by ID. Jobs whose process is gone are
+// marked as exited.
+func loadJobs() ([]*Job, error) {
+	dir, err := jobsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*Job{}
+	for _, file := range files {
+		job, err := loadJob(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil || job.Pid == 0 {
+			// the job is still being created
+			continue
+		}
+		if job.refresh() {
+			job.save()
+		}
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		a, _ := strconv.Atoi(jobs[i].ID)
+		b, _ := strconv.Atoi(jobs[j].ID)
+		return a < b
+	})
+	return jobs, nil
+}
+
+// AddJobsCommand adds the "jobs" command group to parent, to list, tail, stop and clean
+// up the jobs that were started with BashProcess.Detach. Stale jobs are detected on every
+// invocation of the CLI.
+func (cli *cli) AddJobsCommand(parent *Command) {
+	jobs := &Command{
+		Name:      "jobs",
+		ShortDesc: "Manage background jobs",
+		LongDesc:  "Manage the jobs that are running in the background",
+	}
+	cli.AddChild(parent, jobs)
+
+	cli.AddChild(jobs, &Command{
+		Name:      "list",
+		Aliases:   []string{"ls"},
+		ShortDesc: "List background jobs",
+		Behavior:  listJobs,
+	})
+
+	cli.AddChild(jobs, &Command{
+		Name:      "logs",
+		ShortDesc: "Print the output of a background job",
+		Options: struct {
+			Follow bool `short:"f" description:"Keep printing the output until the job exits"`
+		}{},
+		Arguments: jobArgument{},
+		Behavior:  jobLogs,
+	})
+
+	cli.AddChild(jobs, &Command{
+		Name:      "stop",
+		ShortDesc: "Stop a background job",
+		Arguments: jobArgument{},
+		Behavior:  stopJob,
+	})
+
+	cli.AddChild(jobs, &Command{
+		Name:      "clean",
+		ShortDesc: "Remove the jobs that are no longer running, and their logs",
+		Behavior:  cleanJobs,
+	})
+
+	cli.jobs = true
+}
+
+type jobArgument struct {
+	Job string `required:"true" description:"ID of the job"`
+}
+
+func listJobs(ctx *Context) {
+	jobs, err := loadJobs()
+	if err != nil {
+		fatal(newError("%s", err.Error()))
+	}
+
+	rows := [][]string{{"ID", "STATUS", "PID", "STARTED", "COMMAND"}}
+	for _, job := range jobs {
+		status := job.Status
+		if job.ExitCode != nil {
+			status += fmt.Sprintf(" (%d)", *job.ExitCode)
+		}
+		rows = append(rows, []string{job.ID, status, strconv.Itoa(job.Pid), job.StartTime.Format(time.Stamp), job.Command})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(len(cell), widths[i])
+		}
+	}
+	for _, row := range rows {
+		line := ""
+		for i, cell := range row {
+			line += paddedName(cell, widths[i]+2)
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+func jobLogs(ctx *Context) {
+	var opts struct{ Follow bool }
+	var args jobArgument
+	ctx.GetOptions(&opts)
+	ctx.GetArguments(&args)
+
+	job, err := loadJob(args.Job)
+	if err != nil {
+		fatal(newError("%s", err.Error()))
+	}
+
+	log, err := os.Open(job.LogFile)
+	if err != nil {
+		fatal(newError("Could not open the log of job %s. %s", job.ID, err.Error()))
+	}
+	defer log.Close()
+
+	for {
+		running := opts.Follow && job.Status == JobRunning && processAlive(job.Pid, job.ProcessStart)
+		if _, err := io.Copy(os.Stdout, log); err != nil {
+			fatal(newError("%s", err.Error()))
+		}
+		if !running {
+			return
+		}
+
+		select {
+		case <-ctx.Context().Done():
+			return
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func stopJob(ctx *Context) {
+	var args jobArgument
+	ctx.GetArguments(&args)
+
+	job, err := loadJob(args.Job)
+	if err != nil {
+		fatal(newError("%s", err.Error()))
+	}
+	if err := job.Stop(); err != nil {
+		fatal(newError("%s", err.Error()))
+	}
+	fmt.Printf("Stopped job %s%s", job.ID, Sep())
+}
+
+func cleanJobs(ctx *Context) {
+	jobs, err := loadJobs()
+	if err != nil {
+		fatal(newError("%s", err.Error()))
+	}
+
+	dir, err := jobsDir()
+	if err != nil {
+		fatal(newError("%s", err.Error()))
+	}
+
+	removed := 0
+	for _, job := range jobs {
+		if job.Status == JobRunning {
+			continue
+		}
+		os.Remove(job.LogFile)
+		os.Remove(filepath.Join(dir, job.ID+".json"))
+		removed++
+	}
+	fmt.Printf("Removed %d job(s)%s", removed, Sep())
+}
+
+// refreshJobs detects the jobs whose process has exited since the last invocation
+func refreshJobs() {
+	loadJobs()
+}
@@ -0,0 +1,604 @@
+package gocli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a parsed kubectl-style JSONPath template, e.g. "{.items[*].name}". It
+// supports:
+//
+//	$ and @             the root and the current object
+//	.name, ['name']     fields
+//	..name              recursive descent
+//	[n], [-n], [a:b]    indexes and slices of lists
+//	*, [*]              every field or element
+//	[?(@.a == "b")]     filters, with ==, !=, <, <=, > and >=, or just @.a for existence
+//	{range ...}{end}    iteration
+//	{"\n"}              string literals
+//
+// Text outside of braces is printed as is. Like kubectl, a missing field or an index out
+// of range is an error. Filters never fail on missing fields.
+type jsonPath struct {
+	nodes []jsonPathNode
+}
+
+type jsonPathNode struct {
+	text    string
+	steps   []jsonPathStep
+	isRange bool
+	body    []jsonPathNode
+}
+
+type jsonPathStepKind int
+
+const (
+	stepField jsonPathStepKind = iota
+	stepIndex
+	stepWildcard
+	stepSlice
+	stepRecursive
+	stepFilter
+	stepRoot
+	stepCurrent
+)
+
+type jsonPathStep struct {
+	kind       jsonPathStepKind
+	name       string
+	index      int
+	start, end *int
+	filter     *jsonPathFilter
+}
+
+type jsonPathFilter struct {
+	left     []jsonPathStep
+	operator string
+	right    []jsonPathStep
+	literal  interface{}
+}
+
+// parseJSONPath parses a template. Templates without braces are an expression, e.g.
+// ".items[*].name"
+func parseJSONPath(tmpl string) (*jsonPath, error) {
+	if !strings.Contains(tmpl, "{") {
+		tmpl = "{" + tmpl + "}"
+	}
+
+	nodes, rest, err := parseJSONPathNodes(tmpl, false)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JSONPath '%s'. %s", tmpl, err.Error())
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("Invalid JSONPath '%s'. Unexpected {end}", tmpl)
+	}
+	return &jsonPath{nodes: nodes}, nil
+}
+
+// parseJSONPathNodes parses nodes until the end of the template, or until {end} if inRange
+func parseJSONPathNodes(tmpl string, inRange bool) ([]jsonPathNode, string, error) {
+	nodes := []jsonPathNode{}
+	for tmpl != "" {
+		open := strings.Index(tmpl, "{")
+		if open < 0 {
+			nodes = append(nodes, jsonPathNode{text: tmpl})
+			tmpl = ""
+			break
+		}
+		if open > 0 {
+			nodes = append(nodes, jsonPathNode{text: tmpl[:open]})
+		}
+
+		end := matchingBrace(tmpl, open)
+		if end < 0 {
+			return nil, "", fmt.Errorf("Unclosed '{'")
+		}
+		expr := strings.TrimSpace(tmpl[open+1 : end])
+		tmpl = tmpl[end+1:]
+
+		switch {
+		case expr == "end":
+			if !inRange {
+				return nodes, "{end}" + tmpl, nil
+			}
+			return nodes, tmpl, nil
+		case strings.HasPrefix(expr, "range "):
+			steps, err := parseJSONPathSteps(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+			if err != nil {
+				return nil, "", err
+			}
+			body, rest, err := parseJSONPathNodes(tmpl, true)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jsonPathNode{steps: steps, isRange: true, body: body})
+			tmpl = rest
+			continue
+		case strings.HasPrefix(expr, "\""):
+			text, err := strconv.Unquote(expr)
+			if err != nil {
+				return nil, "", fmt.Errorf("Invalid string %s", expr)
+			}
+			nodes = append(nodes, jsonPathNode{text: text})
+		default:
+			steps, err := parseJSONPathSteps(expr)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jsonPathNode{steps: steps})
+		}
+	}
+
+	if inRange {
+		return nil, "", fmt.Errorf("Missing {end}")
+	}
+	return nodes, "", nil
+}
+
+// matchingBrace returns the index of the brace that closes the one at open, ignoring braces
+// in quoted strings
+func matchingBrace(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseJSONPathSteps(expr string) ([]jsonPathStep, error) {
+	steps := []jsonPathStep{}
+	i := 0
+	if expr != "" && expr[0] != '.' && expr[0] != '[' && expr[0] != '$' && expr[0] != '@' {
+		// a bare field name, e.g. "{name}"
+		expr = "." + expr
+	}
+
+	for i < len(expr) {
+		switch c := expr[i]; {
+		case c == '$':
+			steps = append(steps, jsonPathStep{kind: stepRoot})
+			i++
+		case c == '@':
+			steps = append(steps, jsonPathStep{kind: stepCurrent})
+			i++
+		case strings.HasPrefix(expr[i:], ".."):
+			steps = append(steps, jsonPathStep{kind: stepRecursive})
+			i++
+			if i+1 < len(expr) && expr[i+1] == '[' {
+				i++
+			}
+		case c == '.':
+			i++
+			j := i
+			for j < len(expr) && expr[j] != '.' && expr[j] != '[' {
+				j++
+			}
+			name := expr[i:j]
+			if name == "*" {
+				steps = append(steps, jsonPathStep{kind: stepWildcard})
+			} else if name != "" {
+				steps = append(steps, jsonPathStep{kind: stepField, name: name})
+			}
+			i = j
+		case c == '[':
+			end := matchingBracket(expr, i)
+			if end < 0 {
+				return nil, fmt.Errorf("Unclosed '[' in '%s'", expr)
+			}
+			step, err := parseJSONPathBracket(strings.TrimSpace(expr[i+1 : end]))
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			i = end + 1
+		default:
+			return nil, fmt.Errorf("Unexpected '%c' in '%s'", c, expr)
+		}
+	}
+	return steps, nil
+}
+
+func matchingBracket(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseJSONPathBracket(content string) (jsonPathStep, error) {
+	switch {
+	case content == "*":
+		return jsonPathStep{kind: stepWildcard}, nil
+	case strings.HasPrefix(content, "?(") && strings.HasSuffix(content, ")"):
+		filter, err := parseJSONPathFilter(strings.TrimSpace(content[2 : len(content)-1]))
+		return jsonPathStep{kind: stepFilter, filter: filter}, err
+	case len(content) >= 2 && (content[0] == '\'' || content[0] == '"') && content[len(content)-1] == content[0]:
+		return jsonPathStep{kind: stepField, name: content[1 : len(content)-1]}, nil
+	case strings.Contains(content, ":"):
+		parts := strings.SplitN(content, ":", 2)
+		step := jsonPathStep{kind: stepSlice}
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return step, fmt.Errorf("Invalid slice '[%s]'", content)
+			}
+			if i == 0 {
+				step.start = &n
+			} else {
+				step.end = &n
+			}
+		}
+		return step, nil
+	}
+
+	n, err := strconv.Atoi(content)
+	if err != nil {
+		return jsonPathStep{}, fmt.Errorf("Invalid index '[%s]'", content)
+	}
+	return jsonPathStep{kind: stepIndex, index: n}, nil
+}
+
+var jsonPathOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func parseJSONPathFilter(expr string) (*jsonPathFilter, error) {
+	filter := &jsonPathFilter{}
+	left := expr
+	right := ""
+	for _, op := range jsonPathOperators {
+		if i := strings.Index(expr, op); i >= 0 {
+			filter.operator = op
+			left, right = strings.TrimSpace(expr[:i]), strings.TrimSpace(expr[i+len(op):])
+			break
+		}
+	}
+
+	var err error
+	if filter.left, err = parseJSONPathSteps(left); err != nil {
+		return nil, err
+	}
+	if filter.operator == "" {
+		return filter, nil
+	}
+
+	if strings.HasPrefix(right, "@") || strings.HasPrefix(right, "$") {
+		filter.right, err = parseJSONPathSteps(right)
+		return filter, err
+	}
+	if strings.HasPrefix(right, "'") && strings.HasSuffix(right, "'") && len(right) >= 2 {
+		right = strconv.Quote(right[1 : len(right)-1])
+	}
+	literal, err := decodeJSON([]byte(right))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid value '%s' in filter", right)
+	}
+	filter.literal = literal
+	return filter, nil
+}
+
+// decodeJSON decodes content, with numbers as json.Number so that large integers keep
+// their precision
+func decodeJSON(content []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("Unexpected content after the value")
+	}
+	return value, nil
+}
+
+// execute prints the results of the template for v. v is converted to JSON first, so that
+// fields are referenced by their JSON names
+func (p *jsonPath) execute(w io.Writer, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	root, err := decodeJSON(content)
+	if err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	if err := executeJSONPathNodes(&out, p.nodes, root, root); err != nil {
+		return err
+	}
+	txt := out.String()
+	if !strings.HasSuffix(txt, "\n") {
+		txt += "\n"
+	}
+	_, err = io.WriteString(w, txt)
+	return err
+}
+
+func executeJSONPathNodes(out *strings.Builder, nodes []jsonPathNode, root, current interface{}) error {
+	for _, node := range nodes {
+		switch {
+		case node.steps == nil:
+			out.WriteString(node.text)
+		case node.isRange:
+			values, err := evalJSONPath(node.steps, root, current, false)
+			if err != nil {
+				return err
+			}
+			for _, value := range values {
+				if err := executeJSONPathNodes(out, node.body, root, value); err != nil {
+					return err
+				}
+			}
+		default:
+			values, err := evalJSONPath(node.steps, root, current, false)
+			if err != nil {
+				return err
+			}
+			texts := make([]string, len(values))
+			for i, value := range values {
+				texts[i] = formatJSONValue(value)
+			}
+			out.WriteString(strings.Join(texts, " "))
+		}
+	}
+	return nil
+}
+
+func evalJSONPath(steps []jsonPathStep, root, current interface{}, allowMissing bool) ([]interface{}, error) {
+	values := []interface{}{current}
+	for _, step := range steps {
+		next := []interface{}{}
+		for _, value := range values {
+			results, err := evalJSONPathStep(step, root, value, allowMissing)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, results...)
+		}
+		// like kubectl, a field is missing if no value has it
+		if step.kind == stepField && len(values) > 0 && len(next) == 0 && !allowMissing {
+			return nil, fmt.Errorf("%s is not found", step.name)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+func evalJSONPathStep(step jsonPathStep, root, value interface{}, allowMissing bool) ([]interface{}, error) {
+	switch step.kind {
+	case stepRoot:
+		return []interface{}{root}, nil
+	case stepCurrent:
+		return []interface{}{value}, nil
+	case stepField:
+		if object, ok := value.(map[string]interface{}); ok {
+			if field, exists := object[step.name]; exists {
+				return []interface{}{field}, nil
+			}
+		}
+	case stepWildcard:
+		return children(value), nil
+	case stepIndex:
+		if list, ok := value.([]interface{}); ok {
+			i := step.index
+			if i < 0 {
+				i += len(list)
+			}
+			if i >= 0 && i < len(list) {
+				return []interface{}{list[i]}, nil
+			}
+			if !allowMissing {
+				return nil, fmt.Errorf("array index out of bounds: index %d, length %d", step.index, len(list))
+			}
+		}
+	case stepSlice:
+		if list, ok := value.([]interface{}); ok {
+			start, end := 0, len(list)
+			if step.start != nil {
+				start = *step.start
+			}
+			if step.end != nil {
+				end = *step.end
+			}
+			if start < 0 {
+				start += len(list)
+			}
+			if end < 0 {
+				end += len(list)
+			}
+			start = max(min(start, len(list)), 0)
+			end = max(min(end, len(list)), start)
+			return list[start:end], nil
+		}
+	case stepRecursive:
+		return descendants(value), nil
+	case stepFilter:
+		matches := []interface{}{}
+		for _, child := range children(value) {
+			if step.filter.matches(root, child) {
+				matches = append(matches, child)
+			}
+		}
+		return matches, nil
+	}
+	return nil, nil
+}
+
+// children returns the elements of a list, or the fields of an object ordered by name
+func children(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		result := make([]interface{}, len(keys))
+		for i, key := range keys {
+			result[i] = v[key]
+		}
+		return result
+	}
+	return nil
+}
+
+// descendants returns value and everything it contains, depth first
+func descendants(value interface{}) []interface{} {
+	result := []interface{}{value}
+	for _, child := range children(value) {
+		result = append(result, descendants(child)...)
+	}
+	return result
+}
+
+func (f *jsonPathFilter) matches(root, value interface{}) bool {
+	left, _ := evalJSONPath(f.left, root, value, true)
+	if f.operator == "" {
+		return len(left) > 0
+	}
+	if len(left) == 0 {
+		return false
+	}
+
+	right := f.literal
+	if f.right != nil {
+		values, _ := evalJSONPath(f.right, root, value, true)
+		if len(values) == 0 {
+			return false
+		}
+		right = values[0]
+	}
+
+	if order, ok := compareJSONNumbers(left[0], right); ok {
+		return matchesOrder(order, f.operator)
+	}
+
+	switch f.operator {
+	case "==":
+		return formatJSONValue(left[0]) == formatJSONValue(right)
+	case "!=":
+		return formatJSONValue(left[0]) != formatJSONValue(right)
+	}
+	a, aIsString := left[0].(string)
+	b, bIsString := right.(string)
+	if !aIsString || !bIsString {
+		return false
+	}
+	return matchesOrder(strings.Compare(a, b), f.operator)
+}
+
+// compareJSONNumbers compares two numbers, exactly if both are integers. Returns false if
+// one of them is not a number
+func compareJSONNumbers(a, b interface{}) (int, bool) {
+	x, xIsNumber := a.(json.Number)
+	y, yIsNumber := b.(json.Number)
+	if !xIsNumber || !yIsNumber {
+		return 0, false
+	}
+
+	if i, err := x.Int64(); err == nil {
+		if j, err := y.Int64(); err == nil {
+			switch {
+			case i < j:
+				return -1, true
+			case i > j:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	f, errX := x.Float64()
+	g, errY := y.Float64()
+	if errX != nil || errY != nil {
+		return 0, false
+	}
+	switch {
+	case f < g:
+		return -1, true
+	case f > g:
+		return 1, true
+	}
+	return 0, true
+}
+
+// matchesOrder reports whether the result of a comparison satisfies operator
+func matchesOrder(order int, operator string) bool {
+	switch operator {
+	case "==":
+		return order == 0
+	case "!=":
+		return order != 0
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	}
+	return false
+}
+
+// formatJSONValue prints strings and numbers as they are, and lists and objects as JSON
+func formatJSONValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	content, _ := json.Marshal(value)
+	return string(content)
+}
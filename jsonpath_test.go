@@ -0,0 +1,134 @@
+package gocli
+
+import (
+	"strings"
+	"testing"
+)
+
+type jsonPathContainer struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+type jsonPathPod struct {
+	ID         int64               `json:"id"`
+	Name       string              `json:"name"`
+	Replicas   int                 `json:"replicas"`
+	Labels     map[string]string   `json:"labels"`
+	Containers []jsonPathContainer `json:"containers"`
+}
+
+var jsonPathData = struct {
+	Items []jsonPathPod `json:"items"`
+}{
+	Items: []jsonPathPod{
+		{
+			ID:         9007199254740993,
+			Name:       "web",
+			Replicas:   3,
+			Labels:     map[string]string{"tier": "front"},
+			Containers: []jsonPathContainer{{"nginx", "nginx:1"}, {"sidecar", "envoy"}},
+		},
+		{
+			ID:         2,
+			Name:       "db",
+			Replicas:   1,
+			Labels:     map[string]string{"tier": "back"},
+			Containers: []jsonPathContainer{{"pg", "postgres:15"}},
+		},
+	},
+}
+
+func executeJSONPath(tmpl string, v interface{}) (string, error) {
+	p, err := parseJSONPath(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	err = p.execute(&out, v)
+	return out.String(), err
+}
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{"field", "{.items[0].name}", "web"},
+		{"without braces", ".items[1].name", "db"},
+		{"root", "{$.items[1].replicas}", "1"},
+		{"bracket field", "{.items[0]['labels']['tier']}", "front"},
+		{"large integer", "{.items[0].id}", "9007199254740993"},
+		{"negative index", "{.items[-1].name}", "db"},
+		{"wildcard", "{.items[*].name}", "web db"},
+		{"object wildcard", "{.items[0].labels.*}", "front"},
+		{"slice", "{.items[0].containers[0:1].name}", "nginx"},
+		{"open slice", "{.items[0].containers[1:].name}", "sidecar"},
+		{"negative slice", "{.items[0].containers[-1:].name}", "sidecar"},
+		{"slice out of range", "{.items[5:].name}", ""},
+		{"recursive descent", "{..image}", "nginx:1 envoy postgres:15"},
+		{"recursive bracket", "{..['image']}", "nginx:1 envoy postgres:15"},
+		{"filter string", `{.items[?(@.name == "db")].replicas}`, "1"},
+		{"filter single quotes", "{.items[?(@.labels.tier == 'front')].name}", "web"},
+		{"filter number", "{.items[?(@.replicas > 1)].name}", "web"},
+		{"filter not equal", "{.items[?(@.replicas != 3)].name}", "db"},
+		{"filter large integer", "{.items[?(@.id == 9007199254740993)].name}", "web"},
+		{"filter large integer mismatch", "{.items[?(@.id == 9007199254740992)].name}", ""},
+		{"filter existence", "{.items[*].containers[?(@.image)].name}", "nginx sidecar pg"},
+		{"filter missing field", "{.items[?(@.missing == 1)].name}", ""},
+		{"range", `{range .items[*]}{.name}{"\t"}{.replicas}{"\n"}{end}`, "web\t3\ndb\t1\n"},
+		{"nested range", `{range .items[*]}{range .containers[*]}{.name},{end};{end}`, "nginx,sidecar,;pg,;\n"},
+		{"text", "pods: {.items[*].name}", "pods: web db"},
+		{"object", "{.items[1].labels}", `{"tier":"back"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := executeJSONPath(tt.tmpl, jsonPathData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want
+			if !strings.HasSuffix(want, "\n") {
+				want += "\n"
+			}
+			if got != want {
+				t.Errorf("%s = %q, want %q", tt.tmpl, got, want)
+			}
+		})
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		err  string
+	}{
+		{"missing field", "{.items[0].missing}", "missing is not found"},
+		{"missing nested field", "{.items[*].labels.missing}", "missing is not found"},
+		{"index out of range", "{.items[2].name}", "array index out of bounds: index 2, length 2"},
+		{"negative index out of range", "{.items[-3].name}", "array index out of bounds: index -3, length 2"},
+		{"missing field in range", "{range .items[*]}{.missing}{end}", "missing is not found"},
+		{"unclosed brace", "{.items", "Unclosed '{'"},
+		{"unclosed bracket", "{.items[0}", "Unclosed '['"},
+		{"invalid index", "{.items[a]}", "Invalid index '[a]'"},
+		{"invalid slice", "{.items[a:]}", "Invalid slice '[a:]'"},
+		{"missing end", "{range .items[*]}{.name}", "Missing {end}"},
+		{"unexpected end", "{.items}{end}", "Unexpected {end}"},
+		{"invalid filter value", "{.items[?(@.name == web)]}", "Invalid value 'web' in filter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executeJSONPath(tt.tmpl, jsonPathData)
+			if err == nil {
+				t.Fatalf("%s succeeded, want error %q", tt.tmpl, tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s failed with %q, want %q", tt.tmpl, err.Error(), tt.err)
+			}
+		})
+	}
+}
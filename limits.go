@@ -0,0 +1,86 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// ResourceLimits cap the resources of a process. The limits are set with the `ulimit` bash
+// builtin before the command runs, so they also apply to every process the command starts.
+// Zero values mean no limit.
+type ResourceLimits struct {
+	// Maximum CPU time in seconds (RLIMIT_CPU)
+	CPUSeconds uint64
+
+	// Maximum size of the virtual memory in bytes (RLIMIT_AS)
+	AddressSpace uint64
+
+	// Maximum number of open file descriptors (RLIMIT_NOFILE)
+	OpenFiles uint64
+
+	// Niceness to run the command with, from -20 (highest priority) to 19 (lowest)
+	Nice int
+}
+
+// ResourceUsage is the resource usage of a finished process.
+type ResourceUsage struct {
+	UserTime   time.Duration
+	SystemTime time.Duration
+	WallTime   time.Duration
+
+	// Maximum resident set size in bytes. Only reported on Linux
+	MaxRSS int64
+}
+
+func (u *ResourceUsage) String() string {
+	return fmt.Sprintf("user %s, sys %s, wall %s, max rss %d KB", u.UserTime.Round(time.Millisecond), u.SystemTime.Round(time.Millisecond), u.WallTime.Round(time.Millisecond), u.MaxRSS/1024)
+}
+
+// Limits sets the resource limits of the process.
+func (b *BashProcess) Limits(limits ResourceLimits) *BashProcess {
+	if !b.running {
+		b.limits = &limits
+	}
+	return b
+}
+
+// Usage returns the resource usage of the last execution, or nil if the process has not
+// exited yet.
+func (b *BashProcess) Usage() *ResourceUsage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.usage
+}
+
+// limitsScript returns the bash statements that apply the resource limits
+func (b *BashProcess) limitsScript() string {
+	if b.limits == nil {
+		return ""
+	}
+
+	script := ""
+	if b.limits.CPUSeconds > 0 {
+		script += fmt.Sprintf("ulimit -t %d; ", b.limits.CPUSeconds)
+	}
+	if b.limits.AddressSpace > 0 {
+		script += fmt.Sprintf("ulimit -v %d; ", b.limits.AddressSpace/1024)
+	}
+	if b.limits.OpenFiles > 0 {
+		script += fmt.Sprintf("ulimit -n %d; ", b.limits.OpenFiles)
+	}
+	return script
+}
+
+func newResourceUsage(state *os.ProcessState, wallTime time.Duration) *ResourceUsage {
+	if state == nil {
+		return nil
+	}
+
+	return &ResourceUsage{
+		UserTime:   state.UserTime(),
+		SystemTime: state.SystemTime(),
+		WallTime:   wallTime,
+		MaxRSS:     maxRSS(state),
+	}
+}
@@ -15,6 +15,7 @@ type option struct {
 	long        string
 	short       string
 	required    bool
+	secret      bool
 	populated   bool
 	kind        reflect.Kind
 	value       interface{}
@@ -125,6 +126,9 @@ func buildOptionsMap(c *Command) map[string]*option {
 		if required, exists := field.Tag.Lookup("required"); exists && required == "true" {
 			opt.required = true
 		}
+		if secret, exists := field.Tag.Lookup("secret"); exists && secret == "true" {
+			opt.secret = true
+		}
 		if description, exists := field.Tag.Lookup("description"); exists {
 			opt.description = description
 		}
@@ -0,0 +1,410 @@
+package gocli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of Context.Print
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputCSV   = "csv"
+)
+
+const (
+	outputOption   = "output"
+	templateOption = "template"
+	jsonPathOption = "jsonpath"
+)
+
+var (
+	outputFormats = []string{OutputTable, OutputJSON, OutputYAML, OutputCSV}
+
+	// format of the errors printed by the CLI
+	errorFormat = OutputTable
+
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+// EnableOutput adds the built-in option "--output" (or "-o") to every command. It selects
+// the format of Context.Print: table (the default), json, yaml or csv. With json, the
+// errors of the CLI are printed as JSON objects as well.
+//
+// It also adds "--template", to print the value with a Go template, e.g. '{{.Name}}', and
+// "--jsonpath", to extract fields with a kubectl-style JSONPath, e.g. '{.items[*].name}'.
+func (cli *cli) EnableOutput() {
+	cli.addBuiltin(outputOption, "o", reflect.String, "Output format: table, json, yaml or csv")
+	cli.addBuiltin(templateOption, "", reflect.String, "Print the output with a Go template, e.g. '{{.Name}}'")
+	cli.addBuiltin(jsonPathOption, "", reflect.String, "Print the fields of the output selected by a JSONPath, e.g. '{.items[*].name}'")
+}
+
+// configureOutput validates the built-in options of EnableOutput
+func (ctx *Context) configureOutput() error {
+	tmpl, _ := ctx.builtinValue(templateOption).(string)
+	path, _ := ctx.builtinValue(jsonPathOption).(string)
+	if tmpl != "" && path != "" {
+		return fmt.Errorf("Cannot use '--template' and '--jsonpath' together")
+	}
+	if tmpl != "" {
+		t, err := template.New("output").Parse(tmpl)
+		if err != nil {
+			return fmt.Errorf("Invalid template '%s'. %s", tmpl, err.Error())
+		}
+		ctx.outputTemplate = t
+	}
+	if path != "" {
+		p, err := parseJSONPath(path)
+		if err != nil {
+			return err
+		}
+		ctx.outputJSONPath = p
+	}
+
+	format, _ := ctx.builtinValue(outputOption).(string)
+	if format == "" {
+		return nil
+	}
+
+	format = strings.ToLower(format)
+	for _, f := range outputFormats {
+		if f == format {
+			ctx.outputFormat = format
+			errorFormat = format
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid output format '%s'. Expected one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// Print prints v to stdout in the format selected with "--output". Slices, structs and
+// maps are printed as tables with one row per element, and one column per field. Columns
+// are in the order of the fields, and are chosen with the "output" tag:
+//
+//	type Instance struct {
+//		Name   string
+//		Region string `output:"zone"`
+//		Secret string `output:"-"`
+//	}
+//
+// JSON and YAML use the "json" tags of the fields. "--template" and "--jsonpath" take
+// precedence over "--output". Templates reference fields by their Go names, and JSONPaths
+// by their JSON names.
+func (ctx *Context) Print(v interface{}) error {
+	if ctx.outputTemplate != nil {
+		return printTemplate(os.Stdout, ctx.outputTemplate, v)
+	}
+	if ctx.outputJSONPath != nil {
+		if err := ctx.outputJSONPath.execute(os.Stdout, v); err != nil {
+			return fmt.Errorf("Could not print the output with the JSONPath. %s", err.Error())
+		}
+		return nil
+	}
+
+	format := ctx.outputFormat
+	if format == "" {
+		format = OutputTable
+	}
+	return printOutput(os.Stdout, format, v)
+}
+
+// PrintError prints err to stdout, as a JSON object if the output format is JSON.
+func (ctx *Context) PrintError(err error) {
+	printError(err)
+}
+
+func printError(err error) {
+	if errorFormat != OutputJSON {
+		fmt.Println(err)
+		return
+	}
+
+	message := strings.TrimSpace(ansiEscape.ReplaceAllString(err.Error(), ""))
+	message = strings.TrimSpace(strings.TrimPrefix(message, "[ERROR]"))
+	content, _ := json.Marshal(map[string]string{"error": message})
+	fmt.Println(string(content))
+}
+
+func printOutput(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case OutputJSON:
+		content, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Could not print the output as JSON. %s", err.Error())
+		}
+		_, err = fmt.Fprintln(w, string(content))
+		return err
+	case OutputYAML:
+		content, err := marshalYAML(v)
+		if err != nil {
+			return fmt.Errorf("Could not print the output as YAML. %s", err.Error())
+		}
+		_, err = w.Write(content)
+		return err
+	case OutputCSV:
+		header, rows, ok := tabulate(v)
+		if !ok {
+			header, rows = []string{"value"}, [][]string{{formatCell(reflect.ValueOf(v))}}
+		}
+		writer := csv.NewWriter(w)
+		writer.Write(header)
+		writer.WriteAll(rows)
+		return writer.Error()
+	default:
+		header, rows, ok := tabulate(v)
+		if !ok {
+			_, err := fmt.Fprintln(w, formatCell(reflect.ValueOf(v)))
+			return err
+		}
+		_, err := fmt.Fprint(w, renderTable(header, rows))
+		return err
+	}
+}
+
+func printTemplate(w io.Writer, t *template.Template, v interface{}) error {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, v); err != nil {
+		return fmt.Errorf("Could not print the output with the template. %s", err.Error())
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteString("\n")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// marshalYAML converts v to YAML through JSON, so that the "json" tags of the fields apply
+// and the fields keep their order
+func marshalYAML(v interface{}) ([]byte, error) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML, but the nodes keep the JSON style unless it is reset
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return nil, err
+	}
+	resetStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// renderTable aligns the columns like the help text
+func renderTable(header []string, rows [][]string) string {
+	padding := 5
+
+	widths := make([]int, len(header))
+	for i, name := range header {
+		widths[i] = len([]rune(name))
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+
+	txt := ""
+	for i, name := range header {
+		name = strings.ToUpper(name)
+		if i == len(header)-1 {
+			txt += Green("%s", name)
+		} else {
+			txt += paddedNameByLength(Green("%s", name), widths[i]+padding, len([]rune(name)))
+		}
+	}
+	txt += Sep()
+
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(row)-1 {
+				txt += cell
+			} else {
+				txt += paddedNameByLength(cell, widths[i]+padding, len([]rune(cell)))
+			}
+		}
+		txt += Sep()
+	}
+	return txt
+}
+
+// tabulate converts slices, structs and maps to a header and rows. Returns false for other
+// values
+func tabulate(v interface{}) ([]string, [][]string, bool) {
+	value := indirect(reflect.ValueOf(v))
+	if !value.IsValid() {
+		return nil, nil, false
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		header, fields := structColumns(value.Type())
+		return header, [][]string{structRow(value, fields)}, true
+	case reflect.Map:
+		return []string{"key", "value"}, mapRows(value), true
+	case reflect.Slice, reflect.Array:
+	default:
+		return nil, nil, false
+	}
+
+	elemType := value.Type().Elem()
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	rows := [][]string{}
+	if elemType.Kind() == reflect.Struct {
+		header, fields := structColumns(elemType)
+		for i := 0; i < value.Len(); i++ {
+			elem := indirect(value.Index(i))
+			if !elem.IsValid() {
+				rows = append(rows, make([]string, len(header)))
+				continue
+			}
+			rows = append(rows, structRow(elem, fields))
+		}
+		return header, rows, true
+	}
+
+	if elemType.Kind() == reflect.Map {
+		// the columns are the keys of every map
+		keySet := map[string]bool{}
+		for i := 0; i < value.Len(); i++ {
+			elem := indirect(value.Index(i))
+			if elem.IsValid() {
+				for _, key := range elem.MapKeys() {
+					keySet[fmt.Sprint(key.Interface())] = true
+				}
+			}
+		}
+		header := []string{}
+		for key := range keySet {
+			header = append(header, key)
+		}
+		sort.Strings(header)
+
+		for i := 0; i < value.Len(); i++ {
+			elem := indirect(value.Index(i))
+			row := make([]string, len(header))
+			if elem.IsValid() {
+				cells := map[string]string{}
+				for _, key := range elem.MapKeys() {
+					cells[fmt.Sprint(key.Interface())] = formatCell(elem.MapIndex(key))
+				}
+				for j, key := range header {
+					row[j] = cells[key]
+				}
+			}
+			rows = append(rows, row)
+		}
+		return header, rows, true
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		rows = append(rows, []string{formatCell(value.Index(i))})
+	}
+	return []string{"value"}, rows, true
+}
+
+// structColumns returns the column names and the indexes of the fields shown as columns
+func structColumns(t reflect.Type) ([]string, []int) {
+	header := []string{}
+	fields := []int{}
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := convertToJSONCase(field.Name)
+		if tag, exists := field.Tag.Lookup("output"); exists {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		header = append(header, name)
+		fields = append(fields, idx)
+	}
+	return header, fields
+}
+
+func structRow(v reflect.Value, fields []int) []string {
+	row := make([]string, len(fields))
+	for i, idx := range fields {
+		row[i] = formatCell(v.Field(idx))
+	}
+	return row
+}
+
+func mapRows(v reflect.Value) [][]string {
+	rows := [][]string{}
+	for _, key := range v.MapKeys() {
+		rows = append(rows, []string{fmt.Sprint(key.Interface()), formatCell(v.MapIndex(key))})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][0] < rows[j][0] })
+	return rows
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// formatCell formats a value for a single table cell
+func formatCell(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprint(v.Interface())
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatCell(v.Index(i))
+		}
+		return strings.Join(items, ",")
+	case reflect.Struct, reflect.Map:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(v.Interface()); err == nil {
+			return strings.TrimSpace(buf.String())
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,267 @@
+package gocli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParallelJob is a single process that is executed by RunParallel.
+type ParallelJob struct {
+	// Name of the job. Used as the prefix of every output line
+	Name string
+
+	// Command to execute. Defaults to the command set with Process.Cmd
+	Cmd string
+
+	// Process to execute the command with. Defaults to Bash()
+	Process *BashProcess
+}
+
+// ParallelOptions configure RunParallel.
+type ParallelOptions struct {
+	// Maximum number of jobs running at the same time. Zero means no limit
+	Concurrency int
+
+	// Stop starting new jobs and kill the running ones after the first failure
+	FailFast bool
+
+	// Where the prefixed stdout lines are written. Defaults to os.Stdout
+	Stdout io.Writer
+
+	// Where the prefixed stderr lines are written. Defaults to os.Stderr
+	Stderr io.Writer
+}
+
+// JobResult is the outcome of a single ParallelJob.
+type JobResult struct {
+	Name     string
+	ExitCode int
+	Duration time.Duration
+	Err      error
+
+	// Skipped is true if the job was never started because of FailFast
+	Skipped bool
+}
+
+// ParallelReport aggregates the results of RunParallel, in the same order as the jobs.
+type ParallelReport struct {
+	Results []JobResult
+}
+
+// Failed returns the results of the jobs that failed or were skipped.
+func (r *ParallelReport) Failed() []JobResult {
+	failed := []JobResult{}
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns an error summarizing the failed jobs, or nil if all jobs succeeded.
+func (r *ParallelReport) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(failed))
+	for i, result := range failed {
+		names[i] = result.Name
+	}
+	return fmt.Errorf("%d of %d jobs failed: %s", len(failed), len(r.Results), strings.Join(names, ", "))
+}
+
+func (r *ParallelReport) String() string {
+	width := 0
+	for _, result := range r.Results {
+		width = max(len(result.Name), width)
+	}
+
+	txt := ""
+	for _, result := range r.Results {
+		status := Green("OK")
+		if result.Skipped {
+			status = Yellow("SKIPPED")
+		} else if result.Err != nil {
+			status = Red("FAILED")
+		}
+		txt += fmt.Sprintf("  %s  %s (exit code %d, %s)", paddedName(result.Name, width), status, result.ExitCode, result.Duration.Round(time.Millisecond)) + Sep()
+	}
+	return txt
+}
+
+var prefixColors = []func(s string, a ...any) string{Cyan, Magenta, Yellow, Green, Blue, Red}
+
+// RunParallel executes the jobs concurrently. The output of each job is prefixed with its
+// colored name, and is written line by line so that lines of different jobs never interleave.
+// Jobs without a custom stdin do not read from os.Stdin.
+func RunParallel(jobs []ParallelJob, opts ParallelOptions) *ParallelReport {
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = len(jobs)
+	}
+
+	width := 0
+	for _, job := range jobs {
+		width = max(len(job.Name), width)
+	}
+
+	report := &ParallelReport{Results: make([]JobResult, len(jobs))}
+	processes := make([]*BashProcess, len(jobs))
+	for i, job := range jobs {
+		processes[i] = job.Process
+		if processes[i] == nil {
+			processes[i] = Bash()
+		}
+		if job.Cmd == "" {
+			jobs[i].Cmd = processes[i].cmd
+		}
+		report.Results[i] = JobResult{Name: job.Name, ExitCode: -1}
+	}
+
+	var (
+		outputMu  sync.Mutex
+		stateMu   sync.Mutex
+		stopped   bool
+		wg        sync.WaitGroup
+		semaphore = make(chan struct{}, max(concurrency, 1))
+	)
+
+	stop := func() {
+		stateMu.Lock()
+		defer stateMu.Unlock()
+		if stopped {
+			return
+		}
+		stopped = true
+		for _, process := range processes {
+			process.kill()
+		}
+	}
+
+	for i, job := range jobs {
+		semaphore <- struct{}{}
+
+		stateMu.Lock()
+		if stopped {
+			stateMu.Unlock()
+			<-semaphore
+			report.Results[i].Skipped = true
+			report.Results[i].Err = fmt.Errorf("Job '%s' was skipped", job.Name)
+			continue
+		}
+
+		prefix := prefixColors[i%len(prefixColors)]("%s", paddedName(job.Name, width)) + " | "
+		stdout := newPrefixWriter(prefix, opts.Stdout, &outputMu, processes[i].stdoutHandler)
+		stderr := newPrefixWriter(prefix, opts.Stderr, &outputMu, processes[i].stderrHandler)
+
+		var stdin io.Reader
+		if processes[i].stdinReader != nil {
+			stdin = processes[i].stdinReader
+		}
+
+		start := time.Now()
+		err := processes[i].start(job.Cmd, stdin, stdout, stderr)
+		stateMu.Unlock()
+
+		if err != nil {
+			<-semaphore
+			report.Results[i].Err = err
+			if opts.FailFast {
+				stop()
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			err := processes[i].wait()
+			stdout.Flush()
+			stderr.Flush()
+
+			report.Results[i].Duration = time.Since(start)
+			report.Results[i].ExitCode = exitCode(err)
+			report.Results[i].Err = err
+			if err != nil && opts.FailFast {
+				stop()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	return report
+}
+
+// prefixWriter writes complete lines, each prefixed, to out. Partial lines are buffered
+// until they are completed or Flush is called. The mutex is shared between writers so that
+// lines from different writers don't interleave.
+type prefixWriter struct {
+	prefix  string
+	out     io.Writer
+	mu      *sync.Mutex
+	handler StdHandler
+	buf     []byte
+}
+
+func newPrefixWriter(prefix string, out io.Writer, mu *sync.Mutex, handler StdHandler) *prefixWriter {
+	return &prefixWriter{prefix: prefix, out: out, mu: mu, handler: handler}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if w.handler != nil {
+		if err := w.handler(p); err != nil {
+			return 0, err
+		}
+	}
+
+	w.buf = append(w.buf, p...)
+	end := bytes.LastIndexByte(w.buf, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+
+	var lines bytes.Buffer
+	for _, line := range bytes.SplitAfter(w.buf[:end+1], []byte("\n")) {
+		if len(line) > 0 {
+			lines.WriteString(w.prefix)
+			lines.Write(line)
+		}
+	}
+	w.buf = append([]byte{}, w.buf[end+1:]...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.out.Write(lines.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the buffered partial line, if any.
+func (w *prefixWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := fmt.Fprintf(w.out, "%s%s%s", w.prefix, w.buf, Sep())
+	w.buf = nil
+	return err
+}
@@ -0,0 +1,182 @@
+package gocli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ReadMasked reads a line from the terminal like ReadHidden, but echoes an asterisk for
+// every typed character.
+func ReadMasked(prompt string) (string, error) {
+	return readHidden(prompt, true)
+}
+
+// readHidden reads a line without echoing it. If stdin is not a terminal, the line is read
+// from /dev/tty instead, and from stdin if there is no terminal at all
+func readHidden(prompt string, mask bool) (string, error) {
+	if answer, ok, err := scriptedAnswer(promptKey(prompt), prompt, true); ok {
+		return answer, err
+	}
+	return readHiddenLine(prompt, mask)
+}
+
+func readHiddenLine(prompt string, mask bool) (string, error) {
+	if stdinIsTerminal() {
+		fmt.Print(prompt)
+		return readTerminalLine(int(os.Stdin.Fd()), bufferedStdin(), os.Stdout, mask)
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		fmt.Print(prompt)
+		return readLine()
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	return readTerminalLine(int(tty.Fd()), bufio.NewReader(tty), tty, mask)
+}
+
+// readTerminalLine reads a line from the terminal fd in raw mode. Ctrl-C returns
+// ErrInterrupted
+func readTerminalLine(fd int, keys *bufio.Reader, echo io.Writer, mask bool) (string, error) {
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	defer fmt.Fprint(echo, "\r\n")
+
+	line := []rune{}
+	for {
+		r, _, err := keys.ReadRune()
+		if err != nil {
+			return string(line), err
+		}
+
+		switch {
+		case r == '\r' || r == '\n':
+			return string(line), nil
+		case r == 3:
+			return "", ErrInterrupted
+		case r == 4 && len(line) == 0:
+			return "", io.EOF
+		case r == 127 || r == 8:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				if mask {
+					fmt.Fprint(echo, "\b \b")
+				}
+			}
+		case r == 21:
+			// ctrl-u clears the line
+			if mask {
+				fmt.Fprint(echo, strings.Repeat("\b \b", len(line)))
+			}
+			line = line[:0]
+		case unicode.IsPrint(r):
+			line = append(line, r)
+			if mask {
+				fmt.Fprint(echo, "*")
+			}
+		}
+	}
+}
+
+// PasswordPolicy checks a new password, and returns an error that explains why it is too
+// weak.
+type PasswordPolicy func(password string) error
+
+// StrengthPolicy requires passwords of at least minLength characters, that use at least
+// minClasses of the character classes lowercase, uppercase, digits and symbols.
+func StrengthPolicy(minLength, minClasses int) PasswordPolicy {
+	return func(password string) error {
+		if len([]rune(password)) < minLength {
+			return fmt.Errorf("The password must be at least %d characters long", minLength)
+		}
+
+		var lower, upper, digit, symbol bool
+		for _, r := range password {
+			switch {
+			case unicode.IsLower(r):
+				lower = true
+			case unicode.IsUpper(r):
+				upper = true
+			case unicode.IsDigit(r):
+				digit = true
+			default:
+				symbol = true
+			}
+		}
+		classes := 0
+		for _, used := range []bool{lower, upper, digit, symbol} {
+			if used {
+				classes++
+			}
+		}
+		if classes < minClasses {
+			return fmt.Errorf("The password must use at least %d of lowercase letters, uppercase letters, digits and symbols", minClasses)
+		}
+		return nil
+	}
+}
+
+// maximum number of attempts of ReadNewPassword
+const newPasswordAttempts = 3
+
+// ReadNewPassword asks for a new password twice without echoing it, and returns it once
+// both entries match and policy accepts it. A nil policy only rejects empty passwords.
+func ReadNewPassword(prompt string, policy PasswordPolicy) (string, error) {
+	repeat := strings.TrimSuffix(strings.TrimRight(prompt, " "), ":") + " (again): "
+
+	key := promptKey(prompt)
+	if answer, ok, err := scriptedAnswer(key, prompt, true); ok {
+		if err != nil {
+			return "", err
+		}
+		if policy != nil {
+			err = policy(answer)
+		} else if answer == "" {
+			err = fmt.Errorf("The password cannot be empty")
+		}
+		if err != nil {
+			return "", invalidAnswerError(key, err)
+		}
+		return answer, nil
+	}
+
+	var err error
+	for attempt := 0; attempt < newPasswordAttempts; attempt++ {
+		var password, confirmation string
+		password, err = ReadHidden(prompt)
+		if err != nil {
+			return "", err
+		}
+
+		if policy != nil {
+			err = policy(password)
+		} else if password == "" {
+			err = fmt.Errorf("The password cannot be empty")
+		}
+		if err != nil {
+			fmt.Println(Red("%s", err.Error()))
+			continue
+		}
+
+		confirmation, err = ReadHidden(repeat)
+		if err != nil {
+			return "", err
+		}
+		if confirmation != password {
+			err = fmt.Errorf("The passwords do not match")
+			fmt.Println(Red("%s", err.Error()))
+			continue
+		}
+		return password, nil
+	}
+	return "", fmt.Errorf("Could not read a new password after %d attempts. %s", newPasswordAttempts, err.Error())
+}
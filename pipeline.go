@@ -0,0 +1,138 @@
+package gocli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Pipeline connects the stdout of each stage to the stdin of the next stage, the
+// same way `stage1 | stage2 | stage3` does in bash with `set -o pipefail`. The stages
+// are wired together in Go, so each stage keeps its own stdout/stderr handlers.
+type Pipeline struct {
+	stages    []*BashProcess
+	exitCodes []int
+}
+
+// PipelineError is returned by Pipeline.Exec when at least one stage failed. Following
+// pipefail semantics, Stage is the index of the last (rightmost) stage that failed.
+type PipelineError struct {
+	Stage     int
+	Command   string
+	ExitCode  int
+	ExitCodes []int
+	Err       error
+}
+
+func (e *PipelineError) Error() string {
+	codes := make([]string, len(e.ExitCodes))
+	for i, code := range e.ExitCodes {
+		codes[i] = fmt.Sprint(code)
+	}
+	return fmt.Sprintf("Pipeline stage %d '%s' failed with exit code %d (exit codes: [%s])", e.Stage, e.Command, e.ExitCode, strings.Join(codes, " "))
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// Pipe creates a pipeline of processes. The command of each stage is set with
+// BashProcess.Cmd, e.g. Pipe(Bash().Cmd("tar c ."), Bash().Cmd("gzip"))
+func Pipe(stages ...*BashProcess) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// ExitCodes returns the exit code of every stage after Exec has returned. An exit code
+// of -1 means that the stage did not exit normally (e.g. it was killed by a signal)
+func (p *Pipeline) ExitCodes() []int {
+	return p.exitCodes
+}
+
+// Exec runs all stages concurrently and waits for them to finish.
+//
+// The first stage reads from its custom stdin (or os.Stdin) and the last stage writes to
+// its stdout handler (or os.Stdout). A stdout handler on any other stage observes the
+// output as it flows to the next stage.
+func (p *Pipeline) Exec() error {
+	if len(p.stages) == 0 {
+		return fmt.Errorf("Cannot exec pipeline. No stages defined")
+	}
+
+	n := len(p.stages)
+	p.exitCodes = make([]int, n)
+	errs := make([]error, n)
+	closeAfterWait := make([][]io.Closer, n)
+
+	var stdin io.Reader = p.stages[0].stdin()
+	started := 0
+	var startErr error
+	for i, stage := range p.stages {
+		stdout := stage.stdout()
+
+		var pr, pw *os.File
+		if i < n-1 {
+			var err error
+			pr, pw, err = os.Pipe()
+			if err != nil {
+				startErr = err
+				break
+			}
+
+			if stage.stdoutHandler == nil {
+				stdout = pw
+			} else {
+				stdout = io.MultiWriter(stdout, pw)
+			}
+		}
+
+		err := stage.start(stage.cmd, stdin, stdout, stage.stderr())
+
+		// the child processes hold their own copies of the pipe ends
+		if file, ok := stdin.(*os.File); ok && i > 0 {
+			file.Close()
+		}
+		if pw != nil {
+			if stage.stdoutHandler == nil {
+				pw.Close()
+			} else {
+				closeAfterWait[i] = append(closeAfterWait[i], pw)
+			}
+		}
+
+		if err != nil {
+			if pr != nil {
+				pr.Close()
+			}
+			startErr = fmt.Errorf("Could not start pipeline stage %d '%s'. %s", i, stage.cmd, err.Error())
+			break
+		}
+		started++
+		stdin = pr
+	}
+
+	for i := 0; i < started; i++ {
+		errs[i] = p.stages[i].wait()
+		p.exitCodes[i] = exitCode(errs[i])
+		for _, closer := range closeAfterWait[i] {
+			closer.Close()
+		}
+	}
+
+	if startErr != nil {
+		return startErr
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		if errs[i] != nil {
+			return &PipelineError{
+				Stage:     i,
+				Command:   p.stages[i].cmd,
+				ExitCode:  p.exitCodes[i],
+				ExitCodes: p.exitCodes,
+				Err:       errs[i],
+			}
+		}
+	}
+	return nil
+}
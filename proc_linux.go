@@ -0,0 +1,79 @@
+package gocli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// configureProcess sets the platform specific attributes of a child process
+func configureProcess(cmd *exec.Cmd, ownGroup bool) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		// kill the child if the CLI dies
+		Pdeathsig: syscall.SIGKILL,
+		Setpgid:   ownGroup,
+	}
+}
+
+// signalProcess sends sig to p, or to the process group of p if it leads its own group
+func signalProcess(p *os.Process, ownGroup bool, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ownGroup || !ok {
+		return p.Signal(sig)
+	}
+	return syscall.Kill(-p.Pid, s)
+}
+
+func maxRSS(state *os.ProcessState) int64 {
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		// Linux reports the max RSS in kilobytes
+		return rusage.Maxrss * 1024
+	}
+	return 0
+}
+
+// detachProcess starts the child in a new session, so that it outlives the CLI and is
+// not affected by signals sent to the terminal
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// processAlive reports whether the process pid is running. If start is not zero, the
+// process must also have started at start, so that a reused pid is not mistaken for it
+func processAlive(pid int, start uint64) bool {
+	err := syscall.Kill(pid, 0)
+	if err != nil && err != syscall.EPERM {
+		return false
+	}
+	if start == 0 {
+		return true
+	}
+	current, ok := processStartTime(pid)
+	return ok && current == start
+}
+
+// processStartTime returns the start time of the process pid, in clock ticks since boot,
+// from field 22 of /proc/<pid>/stat
+func processStartTime(pid int) (uint64, bool) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, false
+	}
+
+	// the command name (field 2) is in parentheses and can contain spaces
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	// fields starts at field 3
+	if len(fields) < 20 {
+		return 0, false
+	}
+	start, err := strconv.ParseUint(fields[19], 10, 64)
+	return start, err == nil
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+
+package gocli
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func configureProcess(cmd *exec.Cmd, ownGroup bool) {}
+
+func signalProcess(p *os.Process, ownGroup bool, sig os.Signal) error {
+	return p.Signal(sig)
+}
+
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
+
+func detachProcess(cmd *exec.Cmd) {}
+
+func processAlive(pid int, start uint64) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+func processStartTime(pid int) (uint64, bool) {
+	return 0, false
+}
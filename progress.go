@@ -0,0 +1,334 @@
+package gocli
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	progressRefreshInterval = 100 * time.Millisecond
+	progressLogInterval     = 5 * time.Second
+	progressBarWidth        = 30
+)
+
+var spinnerFrames = []string{"-", "\\", "|", "/"}
+
+// PercentRegexp matches percentages like "42%" or "42.5%" in the output of a command.
+var PercentRegexp = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*%`)
+
+// Progress renders spinners and progress bars on stderr. When stderr is a terminal, the
+// bars are redrawn in place. Otherwise their state is logged periodically, one line per
+// bar, so that logs of long runs show that they are still alive. Bars are safe to update
+// from several goroutines.
+type Progress struct {
+	mu      sync.Mutex
+	out     io.Writer
+	tty     bool
+	bars    []*Bar
+	lines   int
+	frame   int
+	lastLog time.Time
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewProgress starts rendering progress on stderr. Stop must be called once the work is done.
+func NewProgress() *Progress {
+	p := &Progress{
+		out:     os.Stderr,
+		tty:     term.IsTerminal(int(os.Stderr.Fd())),
+		lastLog: time.Now(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go p.loop()
+	return p
+}
+
+// AddBar adds a progress bar that is complete once its value reaches total.
+func (p *Progress) AddBar(name string, total int64) *Bar {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	bar := &Bar{progress: p, name: name, total: total, start: time.Now()}
+	p.bars = append(p.bars, bar)
+	return bar
+}
+
+// AddSpinner adds a spinner, for work of unknown length.
+func (p *Progress) AddSpinner(name string) *Bar {
+	return p.AddBar(name, 0)
+}
+
+// Stop renders the final state of the bars and stops rendering.
+func (p *Progress) Stop() {
+	select {
+	case <-p.stop:
+		return
+	default:
+	}
+	close(p.stop)
+	<-p.done
+}
+
+func (p *Progress) loop() {
+	defer close(p.done)
+	ticker := time.NewTicker(progressRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			p.mu.Lock()
+			if p.tty {
+				p.redraw()
+				fmt.Fprintln(p.out)
+			}
+			p.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		p.frame++
+		if p.tty {
+			p.redraw()
+		} else if time.Since(p.lastLog) >= progressLogInterval {
+			p.lastLog = time.Now()
+			for _, bar := range p.bars {
+				if bar.state == barRunning {
+					fmt.Fprintln(p.out, bar.logLine())
+				}
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// redraw replaces the previously drawn bars. Must be called with the lock held
+func (p *Progress) redraw() {
+	width := 0
+	if w, _, err := term.GetSize(int(os.Stderr.Fd())); err == nil {
+		width = w
+	}
+
+	lines := make([]string, len(p.bars))
+	for i, bar := range p.bars {
+		lines[i] = bar.line(p.frame, width)
+	}
+
+	txt := "\r"
+	if p.lines > 1 {
+		txt += fmt.Sprintf("\033[%dA", p.lines-1)
+	}
+	txt += "\033[J" + strings.Join(lines, "\n")
+	fmt.Fprint(p.out, txt)
+	p.lines = len(lines)
+}
+
+type barState int
+
+const (
+	barRunning barState = iota
+	barDone
+	barFailed
+)
+
+// Bar is a progress bar, or a spinner if its total is zero.
+type Bar struct {
+	progress *Progress
+	name     string
+	total    int64
+	current  int64
+	message  string
+	state    barState
+	start    time.Time
+	end      time.Time
+}
+
+// Set sets the current value of the bar.
+func (b *Bar) Set(current int64) {
+	b.progress.mu.Lock()
+	defer b.progress.mu.Unlock()
+	b.current = current
+}
+
+// Add adds n to the current value of the bar.
+func (b *Bar) Add(n int64) {
+	b.progress.mu.Lock()
+	defer b.progress.mu.Unlock()
+	b.current += n
+}
+
+// SetTotal changes the total of the bar. A total of zero turns it into a spinner.
+func (b *Bar) SetTotal(total int64) {
+	b.progress.mu.Lock()
+	defer b.progress.mu.Unlock()
+	b.total = total
+}
+
+// SetMessage sets a short status that is shown after the bar.
+func (b *Bar) SetMessage(message string) {
+	b.progress.mu.Lock()
+	defer b.progress.mu.Unlock()
+	b.message = message
+}
+
+// Done marks the work of the bar as complete.
+func (b *Bar) Done() {
+	b.finish(barDone)
+}
+
+// Fail marks the work of the bar as failed.
+func (b *Bar) Fail() {
+	b.finish(barFailed)
+}
+
+func (b *Bar) finish(state barState) {
+	p := b.progress
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if b.state != barRunning {
+		return
+	}
+	b.state = state
+	b.end = time.Now()
+	if state == barDone && b.total > 0 {
+		b.current = b.total
+	}
+	if !p.tty {
+		fmt.Fprintln(p.out, b.logLine())
+	}
+}
+
+// Handler returns a StdHandler that updates the bar from the output of a command: the
+// first group of re must match a percentage, e.g. PercentRegexp. Lines that do not match
+// are shown as the message of a spinner. If next is not nil, it also receives the output.
+//
+//	bar := progress.AddBar("download", 100)
+//	b := gocli.Bash()
+//	b.HandleStderr(bar.Handler(gocli.PercentRegexp, nil))
+func (b *Bar) Handler(re *regexp.Regexp, next StdHandler) StdHandler {
+	var mu sync.Mutex
+	pending := ""
+	return func(chunk []byte) error {
+		mu.Lock()
+		pending += string(chunk)
+		// progress is often redrawn with carriage returns instead of new lines. The last
+		// segment is only parsed once it is terminated, as it may be cut in the middle
+		end := strings.LastIndexAny(pending, "\r\n")
+		segments := []string{}
+		if end >= 0 {
+			segments = strings.FieldsFunc(pending[:end], func(r rune) bool { return r == '\n' || r == '\r' })
+			pending = pending[end+1:]
+		}
+		if len(pending) > 4096 {
+			pending = pending[len(pending)-4096:]
+		}
+		mu.Unlock()
+
+		for _, segment := range segments {
+			b.update(re, strings.TrimSpace(segment))
+		}
+
+		if next != nil {
+			return next(chunk)
+		}
+		return nil
+	}
+}
+
+func (b *Bar) update(re *regexp.Regexp, segment string) {
+	if segment == "" {
+		return
+	}
+
+	matches := re.FindAllStringSubmatch(segment, -1)
+	if len(matches) == 0 || len(matches[len(matches)-1]) < 2 {
+		b.SetMessage(segment)
+		return
+	}
+
+	percent, err := strconv.ParseFloat(matches[len(matches)-1][1], 64)
+	if err != nil {
+		return
+	}
+	b.progress.mu.Lock()
+	defer b.progress.mu.Unlock()
+	if b.total == 0 {
+		b.total = 100
+	}
+	b.current = int64(percent / 100 * float64(b.total))
+}
+
+func (b *Bar) elapsed() time.Duration {
+	if b.state == barRunning {
+		return time.Since(b.start).Round(time.Second)
+	}
+	return b.end.Sub(b.start).Round(time.Millisecond)
+}
+
+func (b *Bar) percent() float64 {
+	if b.total <= 0 {
+		return 0
+	}
+	return 100 * math.Min(float64(b.current), float64(b.total)) / float64(b.total)
+}
+
+// line renders the bar for a terminal of the given width. Must be called with the lock held
+func (b *Bar) line(frame, width int) string {
+	status := ""
+	switch {
+	case b.state == barDone:
+		status = "done"
+	case b.state == barFailed:
+		status = "failed"
+	case b.total > 0:
+		filled := int(b.percent() / 100 * progressBarWidth)
+		status = "[" + strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled) + "]" + fmt.Sprintf(" %3.0f%%", b.percent())
+	default:
+		status = spinnerFrames[frame%len(spinnerFrames)]
+	}
+
+	txt := fmt.Sprintf("%s %s (%s)", b.name, status, b.elapsed())
+	if b.message != "" && b.state == barRunning {
+		txt += " " + b.message
+	}
+	if width > 0 && len([]rune(txt)) >= width {
+		txt = string([]rune(txt)[:width-1])
+	}
+
+	switch b.state {
+	case barDone:
+		return Green("%s", txt)
+	case barFailed:
+		return Red("%s", txt)
+	}
+	return Cyan("%s", txt)
+}
+
+// logLine describes the bar in a single line for logs. Must be called with the lock held
+func (b *Bar) logLine() string {
+	switch {
+	case b.state == barDone:
+		return fmt.Sprintf("%s: done after %s", b.name, b.elapsed())
+	case b.state == barFailed:
+		return fmt.Sprintf("%s: failed after %s", b.name, b.elapsed())
+	case b.total > 0:
+		return fmt.Sprintf("%s: %.0f%% after %s", b.name, b.percent(), b.elapsed())
+	}
+
+	txt := fmt.Sprintf("%s: running for %s", b.name, b.elapsed())
+	if b.message != "" {
+		txt += ". " + b.message
+	}
+	return txt
+}
@@ -0,0 +1,549 @@
+package gocli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ErrInterrupted is returned by the interactive prompts when the user presses Ctrl-C.
+var ErrInterrupted = errors.New("Interrupted")
+
+// maximum number of choices shown at once by Select and MultiSelect
+const promptPageSize = 10
+
+var (
+	stdinBuffer   *bufio.Reader
+	stdinBufferFd uintptr
+)
+
+// bufferedStdin returns a buffered reader of os.Stdin that is shared by all prompts, so that
+// input that was read ahead is not lost between prompts
+func bufferedStdin() *bufio.Reader {
+	if stdinBuffer == nil || stdinBufferFd != os.Stdin.Fd() {
+		stdinBuffer = bufio.NewReader(os.Stdin)
+		stdinBufferFd = os.Stdin.Fd()
+	}
+	return stdinBuffer
+}
+
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+func readLine() (string, error) {
+	line, err := bufferedStdin().ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+var (
+	rawTerminalsMu sync.Mutex
+	rawTerminals   = map[int]*term.State{}
+)
+
+// makeRaw puts the terminal fd in raw mode, and returns a function that restores it. If the
+// CLI receives SIGINT or SIGTERM in the meantime, the terminal is restored before it exits
+func makeRaw(fd int) (func(), error) {
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	rawTerminalsMu.Lock()
+	rawTerminals[fd] = state
+	rawTerminalsMu.Unlock()
+
+	// without a shutdown handler the default action of the signal would skip the restore
+	stop := make(chan struct{})
+	if !shutdownListening() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			defer signal.Stop(signals)
+			select {
+			case sig := <-signals:
+				restoreTerminals()
+				signal.Stop(signals)
+				raise(sig)
+			case <-stop:
+			}
+		}()
+	}
+
+	return func() {
+		close(stop)
+		rawTerminalsMu.Lock()
+		defer rawTerminalsMu.Unlock()
+		if rawTerminals[fd] == state {
+			delete(rawTerminals, fd)
+			term.Restore(fd, state)
+		}
+	}, nil
+}
+
+// restoreTerminals restores every terminal that is in raw mode
+func restoreTerminals() {
+	rawTerminalsMu.Lock()
+	defer rawTerminalsMu.Unlock()
+	for fd, state := range rawTerminals {
+		term.Restore(fd, state)
+		delete(rawTerminals, fd)
+	}
+}
+
+// raise sends sig to the current process, or exits if that is not supported
+func raise(sig os.Signal) {
+	if p, err := os.FindProcess(os.Getpid()); err == nil && p.Signal(sig) == nil {
+		return
+	}
+	os.Exit(1)
+}
+
+// withRawTerminal runs f with the terminal in raw mode, and always restores it
+func withRawTerminal(f func(keys *bufio.Reader) error) error {
+	restore, err := makeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return f(bufferedStdin())
+}
+
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyEnter     = "enter"
+	keySpace     = "space"
+	keyBackspace = "backspace"
+	keyInterrupt = "ctrl-c"
+	keyUnknown   = ""
+)
+
+// readKey reads a single key press from a terminal in raw mode. Printable characters are
+// returned as they are
+func readKey(keys *bufio.Reader) (string, error) {
+	r, _, err := keys.ReadRune()
+	if err != nil {
+		return keyUnknown, err
+	}
+
+	switch r {
+	case 3:
+		return keyInterrupt, nil
+	case '\r', '\n':
+		return keyEnter, nil
+	case ' ':
+		return keySpace, nil
+	case 127, 8:
+		return keyBackspace, nil
+	case 16:
+		return keyUp, nil
+	case 14:
+		return keyDown, nil
+	case 27:
+		next, err := keys.ReadByte()
+		if err != nil || (next != '[' && next != 'O') {
+			return keyUnknown, err
+		}
+		code, err := keys.ReadByte()
+		if err != nil {
+			return keyUnknown, err
+		}
+		switch code {
+		case 'A':
+			return keyUp, nil
+		case 'B':
+			return keyDown, nil
+		}
+		return keyUnknown, nil
+	}
+
+	if unicode.IsPrint(r) {
+		return string(r), nil
+	}
+	return keyUnknown, nil
+}
+
+// screen redraws the lines of an interactive prompt in place
+type screen struct {
+	lines int
+}
+
+func (s *screen) render(lines []string) {
+	txt := ""
+	if s.lines > 1 {
+		txt += fmt.Sprintf("\033[%dA", s.lines-1)
+	}
+	txt += "\r\033[J" + strings.Join(lines, "\r\n")
+	fmt.Print(txt)
+	s.lines = len(lines)
+}
+
+// done replaces the prompt with a single summary line
+func (s *screen) done(summary string) {
+	s.render([]string{summary})
+	fmt.Print("\r\n")
+}
+
+// Confirm asks a yes/no question. Pressing enter selects def.
+func Confirm(prompt string, def bool) (bool, error) {
+	return confirm(promptKey(prompt), prompt, def)
+}
+
+func confirm(key, prompt string, def bool) (bool, error) {
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+
+	if answer, ok := lookupAnswer(key); ok {
+		printAnswer(fmt.Sprintf("%s %s", prompt, hint), answer)
+		value, valid := parseYesNo(answer, def)
+		if !valid {
+			return def, invalidAnswerError(key, fmt.Errorf("Expected yes or no, got '%s'", answer))
+		}
+		return value, nil
+	}
+	if isAssumeYes() {
+		printAnswer(fmt.Sprintf("%s %s", prompt, hint), "y")
+		return true, nil
+	}
+	if isNonInteractive() {
+		return def, nil
+	}
+
+	if !stdinIsTerminal() {
+		for {
+			fmt.Printf("%s %s ", prompt, hint)
+			line, err := readLine()
+			if err != nil {
+				fmt.Println()
+				return def, err
+			}
+			if answer, ok := parseYesNo(line, def); ok {
+				return answer, nil
+			}
+			fmt.Println(Red("Please answer y or n"))
+		}
+	}
+
+	answer := def
+	err := withRawTerminal(func(keys *bufio.Reader) error {
+		s := &screen{}
+		s.render([]string{fmt.Sprintf("%s %s ", prompt, hint)})
+		for {
+			key, err := readKey(keys)
+			if err != nil {
+				return err
+			}
+			if key == keyInterrupt {
+				s.done(fmt.Sprintf("%s %s", prompt, Red("^C")))
+				return ErrInterrupted
+			}
+
+			if key == keyEnter {
+				key = ""
+			}
+			if value, ok := parseYesNo(key, def); ok {
+				answer = value
+				yesNo := "no"
+				if answer {
+					yesNo = "yes"
+				}
+				s.done(fmt.Sprintf("%s %s", prompt, Cyan(yesNo)))
+				return nil
+			}
+		}
+	})
+	return answer, err
+}
+
+func parseYesNo(s string, def bool) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return def, true
+	case "y", "yes":
+		return true, true
+	case "n", "no":
+		return false, true
+	}
+	return false, false
+}
+
+// Select asks the user to pick one of choices, and returns its index. Choices are picked
+// with the arrow keys, and typing filters them.
+func Select(prompt string, choices []string) (int, error) {
+	return selectChoice(promptKey(prompt), prompt, choices, -1)
+}
+
+// selectChoice is Select with choices[initial] as the default. A negative initial means
+// there is no default
+func selectChoice(key, prompt string, choices []string, initial int) (int, error) {
+	if len(choices) == 0 {
+		return -1, fmt.Errorf("Cannot select from an empty list of choices")
+	}
+
+	if answer, ok := lookupAnswer(key); ok {
+		printAnswer(prompt, answer)
+		i := findChoice(choices, answer)
+		if i < 0 {
+			return -1, invalidAnswerError(key, fmt.Errorf("Expected one of %s, got '%s'", strings.Join(choices, ", "), answer))
+		}
+		return i, nil
+	}
+	if isNonInteractive() {
+		if initial < 0 {
+			return -1, noAnswerError(key)
+		}
+		return initial, nil
+	}
+
+	if !stdinIsTerminal() {
+		for {
+			printNumberedChoices(prompt, choices)
+			if initial >= 0 {
+				fmt.Printf("Enter a number (1-%d) [%d]: ", len(choices), initial+1)
+			} else {
+				fmt.Printf("Enter a number (1-%d): ", len(choices))
+			}
+			line, err := readLine()
+			if err != nil {
+				fmt.Println()
+				return -1, err
+			}
+			if strings.TrimSpace(line) == "" && initial >= 0 {
+				return initial, nil
+			}
+			if i, err := strconv.Atoi(strings.TrimSpace(line)); err == nil && i >= 1 && i <= len(choices) {
+				return i - 1, nil
+			}
+			fmt.Println(Red("Invalid choice '%s'", line))
+		}
+	}
+
+	selected := -1
+	err := withRawTerminal(func(keys *bufio.Reader) error {
+		s := &screen{}
+		filter := ""
+		cursor := max(initial, 0)
+		for {
+			visible := filterChoices(choices, filter)
+			cursor = max(min(cursor, len(visible)-1), 0)
+
+			lines := []string{fmt.Sprintf("%s %s", prompt, Cyan(filter))}
+			if len(visible) == 0 {
+				lines = append(lines, Yellow("  no matches"))
+			}
+			start, end := pageBounds(cursor, len(visible))
+			for i := start; i < end; i++ {
+				if i == cursor {
+					lines = append(lines, Cyan("> %s", choices[visible[i]]))
+				} else {
+					lines = append(lines, "  "+choices[visible[i]])
+				}
+			}
+			s.render(lines)
+
+			key, err := readKey(keys)
+			if err != nil {
+				return err
+			}
+			switch key {
+			case keyInterrupt:
+				s.done(fmt.Sprintf("%s %s", prompt, Red("^C")))
+				return ErrInterrupted
+			case keyUp:
+				cursor = (cursor - 1 + max(len(visible), 1)) % max(len(visible), 1)
+			case keyDown:
+				cursor = (cursor + 1) % max(len(visible), 1)
+			case keyEnter:
+				if len(visible) > 0 {
+					selected = visible[cursor]
+					s.done(fmt.Sprintf("%s %s", prompt, Cyan(choices[selected])))
+					return nil
+				}
+			case keyBackspace:
+				if filter != "" {
+					_, size := utf8.DecodeLastRuneInString(filter)
+					filter = filter[:len(filter)-size]
+				}
+			case keySpace:
+				filter += " "
+			case keyUnknown:
+			default:
+				filter += key
+				cursor = 0
+			}
+		}
+	})
+	return selected, err
+}
+
+// MultiSelect asks the user to pick any number of choices, and returns their indexes.
+// Choices are toggled with space and confirmed with enter.
+func MultiSelect(prompt string, choices []string) ([]int, error) {
+	key := promptKey(prompt)
+	if answer, ok := lookupAnswer(key); ok {
+		printAnswer(prompt, answer)
+		indexes := []int{}
+		for _, item := range strings.Split(answer, ",") {
+			if strings.TrimSpace(item) == "" {
+				continue
+			}
+			i := findChoice(choices, item)
+			if i < 0 {
+				return nil, invalidAnswerError(key, fmt.Errorf("Expected some of %s, got '%s'", strings.Join(choices, ", "), item))
+			}
+			indexes = append(indexes, i)
+		}
+		return indexes, nil
+	}
+	if isNonInteractive() {
+		return []int{}, nil
+	}
+
+	if !stdinIsTerminal() {
+		for {
+			printNumberedChoices(prompt, choices)
+			fmt.Print("Enter numbers separated by commas: ")
+			line, err := readLine()
+			if err != nil {
+				fmt.Println()
+				return nil, err
+			}
+			if indexes, ok := parseNumberList(line, len(choices)); ok {
+				return indexes, nil
+			}
+			fmt.Println(Red("Invalid choices '%s'", line))
+		}
+	}
+
+	checked := make([]bool, len(choices))
+	err := withRawTerminal(func(keys *bufio.Reader) error {
+		s := &screen{}
+		cursor := 0
+		for {
+			lines := []string{fmt.Sprintf("%s %s", prompt, Yellow("(space to toggle, enter to confirm)"))}
+			start, end := pageBounds(cursor, len(choices))
+			for i := start; i < end; i++ {
+				box := "[ ]"
+				if checked[i] {
+					box = Green("[x]")
+				}
+				line := fmt.Sprintf("%s %s", box, choices[i])
+				if i == cursor {
+					lines = append(lines, Cyan(">")+" "+line)
+				} else {
+					lines = append(lines, "  "+line)
+				}
+			}
+			s.render(lines)
+
+			key, err := readKey(keys)
+			if err != nil {
+				return err
+			}
+			switch key {
+			case keyInterrupt:
+				s.done(fmt.Sprintf("%s %s", prompt, Red("^C")))
+				return ErrInterrupted
+			case keyUp:
+				cursor = (cursor - 1 + max(len(choices), 1)) % max(len(choices), 1)
+			case keyDown:
+				cursor = (cursor + 1) % max(len(choices), 1)
+			case keySpace:
+				if len(choices) > 0 {
+					checked[cursor] = !checked[cursor]
+				}
+			case keyEnter:
+				names := []string{}
+				for i, c := range checked {
+					if c {
+						names = append(names, choices[i])
+					}
+				}
+				s.done(fmt.Sprintf("%s %s", prompt, Cyan(strings.Join(names, ", "))))
+				return nil
+			}
+		}
+	})
+
+	indexes := []int{}
+	for i, c := range checked {
+		if c {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, err
+}
+
+func printNumberedChoices(prompt string, choices []string) {
+	fmt.Println(prompt)
+	width := len(strconv.Itoa(len(choices)))
+	for i, choice := range choices {
+		fmt.Printf("  %*d) %s%s", width, i+1, choice, Sep())
+	}
+}
+
+// findChoice returns the index of the choice named answer, or numbered answer from 1.
+// Returns -1 if there is none
+func findChoice(choices []string, answer string) int {
+	answer = strings.TrimSpace(answer)
+	for i, choice := range choices {
+		if choice == answer {
+			return i
+		}
+	}
+	if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(choices) {
+		return i - 1
+	}
+	return -1
+}
+
+// parseNumberList parses comma separated numbers from 1 to n into indexes
+func parseNumberList(line string, n int) ([]int, bool) {
+	indexes := []int{}
+	for _, field := range strings.Split(line, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		i, err := strconv.Atoi(field)
+		if err != nil || i < 1 || i > n {
+			return nil, false
+		}
+		indexes = append(indexes, i-1)
+	}
+	return indexes, true
+}
+
+// filterChoices returns the indexes of the choices that contain filter, ignoring case
+func filterChoices(choices []string, filter string) []int {
+	indexes := []int{}
+	for i, choice := range choices {
+		if strings.Contains(strings.ToLower(choice), strings.ToLower(filter)) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// pageBounds returns the range of choices that is shown so that the cursor is visible
+func pageBounds(cursor, total int) (int, int) {
+	start := max(cursor-promptPageSize+1, 0)
+	return start, min(start+promptPageSize, total)
+}
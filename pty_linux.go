@@ -0,0 +1,183 @@
+package gocli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// openPTY opens a new pseudo-terminal pair using /dev/ptmx
+func openPTY() (master, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("Could not unlock pty. %s", err.Error())
+	}
+
+	n, err := unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("Could not get pty number. %s", err.Error())
+	}
+
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
+
+// inheritSize copies the window size of the terminal attached to from onto to
+func inheritSize(from, to *os.File) {
+	ws, err := unix.IoctlGetWinsize(int(from.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return
+	}
+	unix.IoctlSetWinsize(int(to.Fd()), unix.TIOCSWINSZ, ws)
+}
+
+// startPTY starts b.command with a pseudo-terminal as its stdin, stdout and stderr. Must
+// be called while holding the lock
+func (b *BashProcess) startPTY(stdin io.Reader, stdout io.Writer) error {
+	master, slave, err := openPTY()
+	if err != nil {
+		return err
+	}
+
+	b.command.Stdin = slave
+	b.command.Stdout = slave
+	b.command.Stderr = slave
+	b.command.SysProcAttr.Setsid = true
+	b.command.SysProcAttr.Setctty = true
+	b.command.SysProcAttr.Ctty = 0
+
+	interactive := stdin == io.Reader(os.Stdin) && term.IsTerminal(int(os.Stdin.Fd()))
+	if interactive {
+		inheritSize(os.Stdin, master)
+	}
+
+	if err := b.command.Start(); err != nil {
+		master.Close()
+		slave.Close()
+		return err
+	}
+	slave.Close()
+
+	// propagate terminal size changes
+	winch := make(chan os.Signal, 1)
+	if interactive {
+		signal.Notify(winch, syscall.SIGWINCH)
+		go func() {
+			for range winch {
+				inheritSize(os.Stdin, master)
+			}
+		}()
+	}
+
+	// fully interactive passthrough
+	restore := func() {}
+	if interactive {
+		if r, err := makeRaw(int(os.Stdin.Fd())); err == nil {
+			restore = r
+		}
+	}
+
+	if reader, ok := stdin.(*stdinReader); ok {
+		reader.done = make(chan struct{})
+		b.activeStdin = reader
+	}
+	stopInput := make(chan struct{})
+	input := make(chan struct{})
+	if stdin != nil {
+		go func() {
+			defer close(input)
+			if file, ok := stdin.(*os.File); ok {
+				// a terminal never reaches EOF, so the copy must stop with the command to
+				// not steal the next line of the user
+				last, err := copyUntil(master, file, stopInput)
+				if err == io.EOF {
+					// send EOF (ctrl-d) to the terminal. It only ends the input at the
+					// start of a line, otherwise it sends the pending line first
+					if last != '\n' {
+						master.Write([]byte{4})
+					}
+					master.Write([]byte{4})
+				}
+				return
+			}
+			io.Copy(master, stdin)
+			if _, custom := stdin.(*stdinReader); custom {
+				// send EOF (ctrl-d) to the terminal
+				master.Write([]byte{4})
+			}
+		}()
+	} else {
+		close(input)
+	}
+
+	// reading from the master returns an error (EIO) once all copies of the slave are closed
+	output := make(chan struct{})
+	go func() {
+		io.Copy(stdout, master)
+		close(output)
+	}()
+
+	b.afterWait = append(b.afterWait, func() {
+		<-output
+		close(stopInput)
+		if _, ok := stdin.(*os.File); ok {
+			<-input
+		}
+		master.Close()
+		signal.Stop(winch)
+		close(winch)
+		restore()
+	})
+	return nil
+}
+
+// copyUntil copies src to dst until src reaches EOF or stop is closed, and returns the last
+// byte that was copied. src is polled so that no read is left blocked on it once the copy
+// stops. Returns io.EOF if src reached EOF
+func copyUntil(dst io.Writer, src *os.File, stop chan struct{}) (byte, error) {
+	buf := make([]byte, 32*1024)
+	fds := []unix.PollFd{{Fd: int32(src.Fd()), Events: unix.POLLIN}}
+	var last byte = '\n'
+	for {
+		select {
+		case <-stop:
+			return last, nil
+		default:
+		}
+
+		n, err := unix.Poll(fds, 100)
+		if err == unix.EINTR || (err == nil && n == 0) {
+			continue
+		}
+		if err != nil {
+			return last, err
+		}
+
+		read, err := src.Read(buf)
+		if read > 0 {
+			last = buf[read-1]
+			if _, err := dst.Write(buf[:read]); err != nil {
+				return last, err
+			}
+		}
+		if err != nil {
+			return last, err
+		}
+	}
+}
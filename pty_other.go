@@ -0,0 +1,12 @@
+//go:build !linux
+
+package gocli
+
+import (
+	"fmt"
+	"io"
+)
+
+func (b *BashProcess) startPTY(stdin io.Reader, stdout io.Writer) error {
+	return fmt.Errorf("Cannot exec command. PTY mode is only supported on Linux")
+}
@@ -0,0 +1,149 @@
+package gocli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"regexp"
+	"time"
+)
+
+// RetryPolicy configures how BashProcess.Exec retries a failed command.
+type RetryPolicy struct {
+	// Maximum number of attempts, including the first one
+	MaxAttempts int
+
+	// Delay before the second attempt. Defaults to 1 second
+	InitialBackoff time.Duration
+
+	// Upper bound of the delay between attempts. Zero means no limit
+	MaxBackoff time.Duration
+
+	// Factor the delay is multiplied by after every attempt. Defaults to 2
+	Multiplier float64
+
+	// Fraction (0 to 1) of the delay that is randomly added or subtracted
+	Jitter float64
+
+	// Retry when the command exits with one of these codes. If StderrMatch is also set, a
+	// failure that matches either of them is retried. If both are empty, every failure is
+	ExitCodes []int
+
+	// Retry when the stderr of the failed attempt matches this expression
+	StderrMatch *regexp.Regexp
+}
+
+// RetryAttempt is the outcome of a single attempt.
+type RetryAttempt struct {
+	ExitCode int
+	Duration time.Duration
+	Err      error
+}
+
+// RetryError is returned by Exec when the command did not succeed with a retry policy.
+type RetryError struct {
+	Attempts []RetryAttempt
+}
+
+func (e *RetryError) Error() string {
+	txt := fmt.Sprintf("Command failed after %d attempt(s):", len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		txt += Sep() + fmt.Sprintf("  attempt %d: exit code %d after %s", i+1, attempt.ExitCode, attempt.Duration.Round(time.Millisecond))
+	}
+	return txt
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// Retry makes Exec retry the command according to policy. The start of every attempt is
+// labeled with the attempt number on stderr.
+func (b *BashProcess) Retry(policy RetryPolicy) *BashProcess {
+	if b.running {
+		return b
+	}
+
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = time.Second
+	}
+	if policy.Multiplier <= 0 {
+		policy.Multiplier = 2
+	}
+	b.retry = &policy
+	return b
+}
+
+// shouldRetry reports whether a failure is retried. Without conditions every failure is.
+// Otherwise the failure must match at least one of them
+func (p *RetryPolicy) shouldRetry(err error, stderr []byte) bool {
+	if len(p.ExitCodes) == 0 && p.StderrMatch == nil {
+		return true
+	}
+
+	code := exitCode(err)
+	for _, c := range p.ExitCodes {
+		if c == code {
+			return true
+		}
+	}
+	return p.StderrMatch != nil && p.StderrMatch.Match(stderr)
+}
+
+func (p *RetryPolicy) backoff(attempt int, random *rand.Rand) time.Duration {
+	delay := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		delay = math.Min(delay, float64(p.MaxBackoff))
+	}
+	delay += delay * p.Jitter * (random.Float64()*2 - 1)
+	return time.Duration(math.Max(delay, 0))
+}
+
+func (b *BashProcess) execWithRetry(cmd string) error {
+	policy := b.retry
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	retryErr := &RetryError{}
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && b.stdinReader != nil {
+			b.CustomStdinWithBufferSize(b.stdinPreload, cap(b.stdinReader.lines))
+		}
+
+		// the label is only shown on the terminal, so that handlers receive the output as is
+		prefix := Yellow("[attempt %d/%d]", attempt, policy.MaxAttempts) + " "
+		if policy.MaxAttempts > 1 {
+			fmt.Fprintln(os.Stderr, prefix+"Starting")
+		}
+		var stderrBuf bytes.Buffer
+
+		start := time.Now()
+		err := b.start(cmd, b.stdin(), b.stdout(), io.MultiWriter(b.stderr(), &stderrBuf))
+		if err == nil {
+			err = b.wait()
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		retryErr.Attempts = append(retryErr.Attempts, RetryAttempt{
+			ExitCode: exitCode(err),
+			Duration: time.Since(start),
+			Err:      err,
+		})
+		if attempt >= policy.MaxAttempts || !policy.shouldRetry(err, stderrBuf.Bytes()) {
+			return retryErr
+		}
+
+		delay := policy.backoff(attempt, random)
+		fmt.Fprintln(os.Stderr, prefix+fmt.Sprintf("Failed with exit code %d. Retrying in %s", exitCode(err), delay.Round(time.Millisecond)))
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,193 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// Runner executes shell commands. BashProcess is the default implementation, and
+// FakeRunner provides one for unit tests. Commands should get their runners from
+// Context.Runner so that they can be replaced in tests.
+type Runner interface {
+	HandleStdout(handler StdHandler)
+	HandleStderr(handler StdHandler)
+	CustomStdin(preload []string)
+	Stdin(line string)
+	Exec(cmd string) error
+}
+
+var _ Runner = (*BashProcess)(nil)
+
+// FakeRunner returns canned output for commands instead of executing them, and records
+// every invocation.
+//
+//	fake := gocli.NewFakeRunner()
+//	fake.On("git rev-parse HEAD").Stdout("abc123\n")
+//	fake.OnRegexp(`^git push`).Stderr("rejected\n").ExitCode(1)
+//	ctx.SetRunner(fake.New)
+type FakeRunner struct {
+	mu          sync.Mutex
+	responses   []*FakeResponse
+	invocations []FakeInvocation
+}
+
+// FakeResponse is the canned result of a command run by a FakeRunner.
+type FakeResponse struct {
+	cmd      string
+	re       *regexp.Regexp
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// FakeInvocation is a command that was executed by a FakeRunner.
+type FakeInvocation struct {
+	Cmd   string
+	Stdin []string
+}
+
+// FakeExitError is returned by a FakeRunner for responses with a non-zero exit code. Like
+// *exec.ExitError, it implements ExitCode() int.
+type FakeExitError struct {
+	Code int
+}
+
+func (e *FakeExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *FakeExitError) ExitCode() int {
+	return e.Code
+}
+
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{}
+}
+
+// On defines the response for commands that are exactly equal to cmd. Responses are
+// matched in the order they were defined.
+func (f *FakeRunner) On(cmd string) *FakeResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	response := &FakeResponse{cmd: cmd}
+	f.responses = append(f.responses, response)
+	return response
+}
+
+// OnRegexp defines the response for commands that match the regular expression pattern.
+// Responses are matched in the order they were defined.
+func (f *FakeRunner) OnRegexp(pattern string) *FakeResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	response := &FakeResponse{re: regexp.MustCompile(pattern)}
+	f.responses = append(f.responses, response)
+	return response
+}
+
+func (r *FakeResponse) Stdout(stdout string) *FakeResponse {
+	r.stdout = stdout
+	return r
+}
+
+func (r *FakeResponse) Stderr(stderr string) *FakeResponse {
+	r.stderr = stderr
+	return r
+}
+
+func (r *FakeResponse) ExitCode(code int) *FakeResponse {
+	r.exitCode = code
+	return r
+}
+
+func (r *FakeResponse) matches(cmd string) bool {
+	if r.re != nil {
+		return r.re.MatchString(cmd)
+	}
+	return r.cmd == cmd
+}
+
+// New returns a Runner that uses the responses of f. It can be passed to Context.SetRunner.
+func (f *FakeRunner) New() Runner {
+	return &fakeProcess{runner: f}
+}
+
+// Invocations returns every command that was executed, in order.
+func (f *FakeRunner) Invocations() []FakeInvocation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	invocations := make([]FakeInvocation, len(f.invocations))
+	copy(invocations, f.invocations)
+	return invocations
+}
+
+func (f *FakeRunner) record(cmd string, stdin []string) *FakeResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.invocations = append(f.invocations, FakeInvocation{Cmd: cmd, Stdin: stdin})
+	for _, response := range f.responses {
+		if response.matches(cmd) {
+			return response
+		}
+	}
+	return nil
+}
+
+type fakeProcess struct {
+	runner        *FakeRunner
+	stdoutHandler StdHandler
+	stderrHandler StdHandler
+	stdin         []string
+}
+
+func (p *fakeProcess) HandleStdout(handler StdHandler) {
+	p.stdoutHandler = handler
+}
+
+func (p *fakeProcess) HandleStderr(handler StdHandler) {
+	p.stderrHandler = handler
+}
+
+func (p *fakeProcess) CustomStdin(preload []string) {
+	p.stdin = append([]string{}, preload...)
+}
+
+func (p *fakeProcess) Stdin(line string) {
+	p.stdin = append(p.stdin, line)
+}
+
+func (p *fakeProcess) Exec(cmd string) error {
+	response := p.runner.record(cmd, p.stdin)
+	if response == nil {
+		return fmt.Errorf("FakeRunner has no response defined for command '%s'", cmd)
+	}
+
+	if err := writeOutput(response.stdout, p.stdoutHandler, os.Stdout); err != nil {
+		return err
+	}
+	if err := writeOutput(response.stderr, p.stderrHandler, os.Stderr); err != nil {
+		return err
+	}
+
+	if response.exitCode != 0 {
+		return &FakeExitError{Code: response.exitCode}
+	}
+	return nil
+}
+
+// writeOutput passes output to the handler, or writes it to file if there is no handler
+func writeOutput(output string, handler StdHandler, file *os.File) error {
+	if output == "" {
+		return nil
+	}
+	if handler == nil {
+		_, err := file.WriteString(output)
+		return err
+	}
+	return handler([]byte(output))
+}
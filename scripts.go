@@ -0,0 +1,261 @@
+package gocli
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// AddScripts registers every bash script (*.sh or *.bash) in the directory dir of fsys
+// as a child command of parent. The name of the command is the file name without its
+// extension. Use os.DirFS for a directory on disk, or an embed.FS.
+//
+// The options and arguments of the command are declared in the comment header of the script:
+//
+//	#!/usr/bin/env bash
+//	# @description Deploy the application
+//	# @alias dep
+//	# @option --region,-r string required Region to deploy to
+//	# @option --dry-run bool Only print what would be deployed
+//	# @arg target string required Name of the deployment target
+//
+// Types are string, bool, int and float. The script receives the options as environment
+// variables (OPT_REGION, OPT_DRY_RUN) and the arguments both as positional parameters and as
+// environment variables (ARG_TARGET).
+func (cli *cli) AddScripts(parent *Command, fsys fs.FS, dir string) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		fatal(newSetupError("Could not read scripts directory '%s'. %s", dir, err.Error()))
+	}
+
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".sh" && ext != ".bash") {
+			continue
+		}
+
+		file := path.Join(dir, entry.Name())
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			fatal(newSetupError("Could not read script '%s'. %s", file, err.Error()))
+		}
+
+		command, err := scriptCommand(strings.TrimSuffix(entry.Name(), ext), string(content))
+		if err != nil {
+			fatal(newSetupError("Invalid header in script '%s'. %s", file, err.Error()))
+		}
+		cli.AddChild(parent, command)
+	}
+}
+
+// dynamicField is an option or argument that is declared at runtime, e.g. in a script
+// header or a tasks file
+type dynamicField struct {
+	name        string
+	short       string
+	kind        string
+	required    bool
+	description string
+	def         string
+}
+
+var dynamicTypes = map[string]reflect.Type{
+	"string": reflect.TypeOf(""),
+	"bool":   reflect.TypeOf(false),
+	"int":    reflect.TypeOf(0),
+	"float":  reflect.TypeOf(float64(0)),
+}
+
+func scriptCommand(name, content string) (*Command, error) {
+	command := &Command{Name: name}
+	options := []dynamicField{}
+	arguments := []dynamicField{}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#!") || line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			// the header ends with the first line of code
+			break
+		}
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		if !strings.HasPrefix(line, "@") {
+			continue
+		}
+		directive, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+
+		switch directive {
+		case "@description":
+			command.ShortDesc = value
+			if command.LongDesc == "" {
+				command.LongDesc = value
+			}
+		case "@long":
+			command.LongDesc = value
+		case "@alias":
+			command.Aliases = append(command.Aliases, strings.Fields(value)...)
+		case "@option":
+			field, err := parseScriptField(value, true)
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, field)
+		case "@arg":
+			field, err := parseScriptField(value, false)
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, field)
+		default:
+			return nil, fmt.Errorf("Unknown directive '%s'", directive)
+		}
+	}
+
+	var err error
+	if len(options) > 0 {
+		if command.Options, err = dynamicStruct(options); err != nil {
+			return nil, err
+		}
+	}
+	if len(arguments) > 0 {
+		if command.Arguments, err = dynamicStruct(arguments); err != nil {
+			return nil, err
+		}
+	}
+	command.Behavior = func(ctx *Context) {
+		runScript(ctx, name, content)
+	}
+	return command, nil
+}
+
+// parseScriptField parses "--name,-n type [required] description" for options, and
+// "name type [required] description" for arguments
+func parseScriptField(value string, isOption bool) (dynamicField, error) {
+	words := strings.Fields(value)
+	if len(words) < 2 {
+		return dynamicField{}, fmt.Errorf("Expected a name and a type, got '%s'", value)
+	}
+
+	field := dynamicField{kind: words[1]}
+	if isOption {
+		for _, flag := range strings.Split(words[0], ",") {
+			if isLongFlag(flag) {
+				field.name = flag[2:]
+			} else if isShortFlag(flag) {
+				field.short = flag[1:]
+			} else {
+				return dynamicField{}, fmt.Errorf("Invalid option flag '%s'", flag)
+			}
+		}
+		if field.name == "" {
+			return dynamicField{}, fmt.Errorf("Option '%s' needs a long name", words[0])
+		}
+	} else {
+		field.name = words[0]
+	}
+
+	if _, ok := dynamicTypes[field.kind]; !ok {
+		return dynamicField{}, fmt.Errorf("Invalid type '%s' for '%s'. Allowed types are string, bool, int and float", field.kind, field.name)
+	}
+
+	rest := words[2:]
+	if len(rest) > 0 && rest[0] == "required" {
+		field.required = true
+		rest = rest[1:]
+	}
+	field.description = strings.Join(rest, " ")
+	return field, nil
+}
+
+// dynamicStruct builds a struct value with one field per declared option or argument, so
+// that they are parsed the same way as the options and arguments of any other command
+func dynamicStruct(fields []dynamicField) (interface{}, error) {
+	structFields := make([]reflect.StructField, len(fields))
+	for i, field := range fields {
+		tag := fmt.Sprintf(`description:%q`, field.description)
+		if field.short != "" {
+			tag += fmt.Sprintf(` short:%q`, field.short)
+		}
+		if field.required {
+			tag += ` required:"true"`
+		}
+
+		name := convertToCamelCase(field.name)
+		if !isExportedIdentifier(name) {
+			return nil, fmt.Errorf("Invalid name '%s'. Names must start with a letter, and only contain letters, digits, '_' and '-'", field.name)
+		}
+
+		structFields[i] = reflect.StructField{
+			Name: name,
+			Type: dynamicTypes[field.kind],
+			Tag:  reflect.StructTag(tag),
+		}
+	}
+	value := reflect.New(reflect.StructOf(structFields)).Elem()
+	for i, field := range fields {
+		if field.def == "" {
+			continue
+		}
+		def, err := cast(field.def, dynamicTypes[field.kind].Kind())
+		if err != nil {
+			return nil, fmt.Errorf("Invalid default for '%s'. %s", field.name, err.Error())
+		}
+		value.Field(i).Set(reflect.ValueOf(def))
+	}
+	return value.Interface(), nil
+}
+
+// isExportedIdentifier reports whether name can be the name of an exported struct field
+func isExportedIdentifier(name string) bool {
+	for i, r := range name {
+		if i == 0 && !unicode.IsUpper(r) {
+			return false
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return name != ""
+}
+
+func envName(prefix, name string) string {
+	return prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func runScript(ctx *Context, name, content string) {
+	process := Bash()
+	process.argv0 = name
+
+	options := make([]*option, len(ctx.options))
+	copy(options, ctx.options)
+	sort.Sort(Bylong(options))
+	for _, option := range options {
+		if option.long != "help" {
+			process.Env(envName("OPT_", option.long), fmt.Sprint(option.value))
+		}
+	}
+
+	args := []string{}
+	for _, argument := range ctx.arguments {
+		value := fmt.Sprint(argument.value)
+		process.Env(envName("ARG_", argument.name), value)
+		if argument.populated {
+			args = append(args, value)
+		}
+	}
+	process.Args(args...)
+
+	if err := process.Exec(content); err != nil {
+		exit(max(exitCode(err), 1))
+	}
+}
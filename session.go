@@ -0,0 +1,323 @@
+package gocli
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Session is the fixture that is written by a Recorder and replayed by a Replayer.
+type Session struct {
+	Invocations []SessionInvocation `json:"invocations"`
+}
+
+// SessionInvocation is a single command of a recorded Session.
+type SessionInvocation struct {
+	Cmd      string         `json:"cmd"`
+	Stdin    []string       `json:"stdin,omitempty"`
+	Output   []SessionChunk `json:"output,omitempty"`
+	ExitCode int            `json:"exitCode"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// SessionChunk is a piece of stdout or stderr of a recorded command, along with the time
+// since the command was started. Data that is not valid UTF-8, e.g. the output of gzip, is
+// encoded with base64 and Encoding is "base64", so that it is replayed byte for byte.
+type SessionChunk struct {
+	Stream   string        `json:"stream"`
+	Offset   time.Duration `json:"offset"`
+	Data     string        `json:"data"`
+	Encoding string        `json:"encoding,omitempty"`
+}
+
+const base64Encoding = "base64"
+
+func newSessionChunk(stream string, offset time.Duration, data []byte) SessionChunk {
+	chunk := SessionChunk{Stream: stream, Offset: offset, Data: string(data)}
+	if !utf8.Valid(data) {
+		chunk.Data = base64.StdEncoding.EncodeToString(data)
+		chunk.Encoding = base64Encoding
+	}
+	return chunk
+}
+
+// decode returns the data of the chunk
+func (c SessionChunk) decode() (string, error) {
+	if c.Encoding != base64Encoding {
+		return c.Data, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(c.Data)
+	if err != nil {
+		return "", fmt.Errorf("Could not decode recorded output. %s", err.Error())
+	}
+	return string(data), nil
+}
+
+const (
+	streamStdout = "stdout"
+	streamStderr = "stderr"
+)
+
+// Recorder executes commands with a real Runner and writes every invocation to a fixture
+// file that can later be replayed with a Replayer.
+//
+//	rec := gocli.NewRecorder("testdata/deploy.json", nil)
+//	ctx.SetRunner(rec.New)
+type Recorder struct {
+	mu        sync.Mutex
+	path      string
+	newRunner func() Runner
+	session   Session
+}
+
+// NewRecorder creates a Recorder that writes to path. Commands are executed with runners
+// from newRunner, which defaults to Bash().
+func NewRecorder(path string, newRunner func() Runner) *Recorder {
+	if newRunner == nil {
+		newRunner = func() Runner { return Bash() }
+	}
+	return &Recorder{path: path, newRunner: newRunner}
+}
+
+// New returns a Runner that records its invocations. It can be passed to Context.SetRunner.
+func (r *Recorder) New() Runner {
+	return &recordingProcess{recorder: r, runner: r.newRunner()}
+}
+
+func (r *Recorder) record(invocation SessionInvocation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.session.Invocations = append(r.session.Invocations, invocation)
+	data, err := json.MarshalIndent(r.session, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0644)
+}
+
+type recordingProcess struct {
+	recorder      *Recorder
+	runner        Runner
+	stdoutHandler StdHandler
+	stderrHandler StdHandler
+	stdin         []string
+}
+
+func (p *recordingProcess) HandleStdout(handler StdHandler) {
+	p.stdoutHandler = handler
+}
+
+func (p *recordingProcess) HandleStderr(handler StdHandler) {
+	p.stderrHandler = handler
+}
+
+func (p *recordingProcess) CustomStdin(preload []string) {
+	p.stdin = append([]string{}, preload...)
+	p.runner.CustomStdin(preload)
+}
+
+func (p *recordingProcess) Stdin(line string) {
+	p.stdin = append(p.stdin, line)
+	p.runner.Stdin(line)
+}
+
+func (p *recordingProcess) Exec(cmd string) error {
+	var mu sync.Mutex
+	invocation := SessionInvocation{Cmd: cmd}
+	start := time.Now()
+
+	recordStream := func(stream string, handler StdHandler, file *os.File) StdHandler {
+		return func(line []byte) error {
+			mu.Lock()
+			invocation.Output = append(invocation.Output, newSessionChunk(stream, time.Since(start), line))
+			mu.Unlock()
+			return writeOutput(string(line), handler, file)
+		}
+	}
+	p.runner.HandleStdout(recordStream(streamStdout, p.stdoutHandler, os.Stdout))
+	p.runner.HandleStderr(recordStream(streamStderr, p.stderrHandler, os.Stderr))
+
+	err := p.runner.Exec(cmd)
+
+	invocation.Stdin = p.stdin
+	invocation.ExitCode = exitCode(err)
+	if err != nil {
+		invocation.Error = err.Error()
+	}
+	if recordErr := p.recorder.record(invocation); recordErr != nil {
+		return fmt.Errorf("Could not record session to '%s'. %s", p.recorder.path, recordErr.Error())
+	}
+	return err
+}
+
+// Replayer replays a Session that was recorded with a Recorder without executing anything.
+// Commands must be executed in the same order, with the same stdin, as when they were
+// recorded. Recorded output is replayed immediately, in order.
+//
+//	replay, err := gocli.LoadReplayer("testdata/deploy.json")
+//	ctx.SetRunner(replay.New)
+//	...
+//	err = replay.Done()
+type Replayer struct {
+	mu      sync.Mutex
+	path    string
+	session Session
+	next    int
+}
+
+// LoadReplayer reads a fixture file that was written by a Recorder.
+func LoadReplayer(path string) (*Replayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read session '%s'. %s", path, err.Error())
+	}
+
+	replayer := &Replayer{path: path}
+	if err := json.Unmarshal(data, &replayer.session); err != nil {
+		return nil, fmt.Errorf("Could not parse session '%s'. %s", path, err.Error())
+	}
+	return replayer, nil
+}
+
+// New returns a Runner that replays the session. It can be passed to Context.SetRunner.
+func (r *Replayer) New() Runner {
+	return &replayProcess{replayer: r}
+}
+
+// Done returns an error if some recorded invocations were not replayed.
+func (r *Replayer) Done() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next < len(r.session.Invocations) {
+		remaining := []string{}
+		for _, invocation := range r.session.Invocations[r.next:] {
+			remaining = append(remaining, invocation.Cmd)
+		}
+		return fmt.Errorf("Session '%s' has %d commands that were not executed:%s  %s", r.path, len(remaining), Sep(), strings.Join(remaining, Sep()+"  "))
+	}
+	return nil
+}
+
+func (r *Replayer) take(cmd string, stdin []string) (*SessionInvocation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next >= len(r.session.Invocations) {
+		return nil, fmt.Errorf("Session '%s' has no more recorded commands. Unexpected command:%s%s", r.path, Sep(), lineDiff("", cmd))
+	}
+
+	invocation := &r.session.Invocations[r.next]
+	if invocation.Cmd != cmd {
+		return nil, fmt.Errorf("Command %d does not match session '%s':%s%s", r.next, r.path, Sep(), lineDiff(invocation.Cmd, cmd))
+	}
+
+	expected, actual := strings.Join(invocation.Stdin, ""), strings.Join(stdin, "")
+	if expected != actual {
+		return nil, fmt.Errorf("Stdin of command %d '%s' does not match session '%s':%s%s", r.next, cmd, r.path, Sep(), lineDiff(expected, actual))
+	}
+
+	r.next++
+	return invocation, nil
+}
+
+type replayProcess struct {
+	replayer      *Replayer
+	stdoutHandler StdHandler
+	stderrHandler StdHandler
+	stdin         []string
+}
+
+func (p *replayProcess) HandleStdout(handler StdHandler) {
+	p.stdoutHandler = handler
+}
+
+func (p *replayProcess) HandleStderr(handler StdHandler) {
+	p.stderrHandler = handler
+}
+
+func (p *replayProcess) CustomStdin(preload []string) {
+	p.stdin = append([]string{}, preload...)
+}
+
+func (p *replayProcess) Stdin(line string) {
+	p.stdin = append(p.stdin, line)
+}
+
+func (p *replayProcess) Exec(cmd string) error {
+	invocation, err := p.replayer.take(cmd, p.stdin)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range invocation.Output {
+		handler, file := p.stdoutHandler, os.Stdout
+		if chunk.Stream == streamStderr {
+			handler, file = p.stderrHandler, os.Stderr
+		}
+		data, err := chunk.decode()
+		if err != nil {
+			return err
+		}
+		if err := writeOutput(data, handler, file); err != nil {
+			return err
+		}
+	}
+
+	if invocation.ExitCode != 0 {
+		return &FakeExitError{Code: invocation.ExitCode}
+	}
+	if invocation.Error != "" {
+		return errors.New(invocation.Error)
+	}
+	return nil
+}
+
+// lineDiff returns a line based diff between expected and actual. Lines only in expected
+// are prefixed with "-", lines only in actual with "+"
+func lineDiff(expected, actual string) string {
+	a, b := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+	if expected == "" {
+		a = []string{}
+	}
+
+	// longest common subsequence
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	txt := ""
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			txt += "  " + a[i] + Sep()
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			txt += Red("- %s", a[i]) + Sep()
+			i++
+		default:
+			txt += Green("+ %s", b[j]) + Sep()
+			j++
+		}
+	}
+	return txt
+}
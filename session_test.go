@@ -0,0 +1,46 @@
+package gocli
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionReplaysBinaryOutput(t *testing.T) {
+	binary := string([]byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe, 'a', '\n'})
+	fake := NewFakeRunner()
+	fake.On("gzip -c").Stdout(binary)
+	fake.On("echo hi").Stdout("hi\n")
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	recorder := NewRecorder(path, fake.New)
+	for _, cmd := range []string{"gzip -c", "echo hi"} {
+		runner := recorder.New()
+		runner.HandleStdout(func([]byte) error { return nil })
+		if err := runner.Exec(cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replayer, err := LoadReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ cmd, want string }{{"gzip -c", binary}, {"echo hi", "hi\n"}} {
+		var out bytes.Buffer
+		runner := replayer.New()
+		runner.HandleStdout(func(chunk []byte) error {
+			out.Write(chunk)
+			return nil
+		})
+		if err := runner.Exec(tt.cmd); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s replayed %q, want %q", tt.cmd, out.String(), tt.want)
+		}
+	}
+	if err := replayer.Done(); err != nil {
+		t.Fatal(err)
+	}
+}
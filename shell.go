@@ -0,0 +1,150 @@
+package gocli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Raw marks a value that should be interpolated into a shell command without quoting.
+// Only use it for values that are trusted, e.g. flags that are hardcoded by the caller.
+type Raw string
+
+const shellQuoteFunc = "__gocli_shellquote"
+
+// ShellQuote returns s quoted so that POSIX shells interpret it as a single literal word.
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	safe := true
+	for _, r := range s {
+		if !isShellSafe(r) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func isShellSafe(r rune) bool {
+	return (r >= 'a' && r <= 'z') ||
+		(r >= 'A' && r <= 'Z') ||
+		(r >= '0' && r <= '9') ||
+		strings.ContainsRune("@%+=:,./-_", r)
+}
+
+// Cmd renders the command template tmpl (text/template syntax) with data. Every value
+// that is interpolated is quoted with ShellQuote, unless it is of type Raw or the
+// action is piped through the "raw" function, e.g. `{{.Flags | raw}}`.
+//
+// Slices are expanded into multiple quoted words. Option and argument structs that are
+// populated by Context.GetOptions/Context.GetArguments can be passed directly as data.
+//
+//	cmd, err := gocli.Cmd("git checkout {{.Branch}}", opts)
+func Cmd(tmpl string, data interface{}) (string, error) {
+	return renderCmd(tmpl, data)
+}
+
+// renderCmd renders tmpl like Cmd, with the text/template options, e.g. "missingkey=error"
+func renderCmd(tmpl string, data interface{}, options ...string) (string, error) {
+	t, err := parseCmdTemplate(tmpl)
+	if err != nil {
+		return "", err
+	}
+	t.Option(options...)
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("Could not render command template. %s", err.Error())
+	}
+	return sb.String(), nil
+}
+
+func parseCmdTemplate(tmpl string) (*template.Template, error) {
+	t, err := template.New("cmd").Funcs(template.FuncMap{
+		shellQuoteFunc: shellQuoteValue,
+		"raw":          func(v interface{}) Raw { return Raw(fmt.Sprint(v)) },
+	}).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse command template. %s", err.Error())
+	}
+
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil {
+			escapeList(tt.Tree.Root)
+		}
+	}
+	return t, nil
+}
+
+// escapeList appends the quoting function to every action that writes to the output.
+// It is the same approach that html/template takes to escape its actions.
+func escapeList(list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			// actions that only declare variables don't produce output
+			if len(n.Pipe.Decl) > 0 {
+				continue
+			}
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+				NodeType: parse.NodeCommand,
+				Args:     []parse.Node{parse.NewIdentifier(shellQuoteFunc)},
+			})
+		case *parse.IfNode:
+			escapeList(n.List)
+			escapeList(n.ElseList)
+		case *parse.RangeNode:
+			escapeList(n.List)
+			escapeList(n.ElseList)
+		case *parse.WithNode:
+			escapeList(n.List)
+			escapeList(n.ElseList)
+		}
+	}
+}
+
+func shellQuoteValue(v interface{}) string {
+	if raw, ok := v.(Raw); ok {
+		return string(raw)
+	}
+
+	value := reflect.ValueOf(v)
+	// byte slices, e.g. json.RawMessage, are text and not lists of numbers
+	if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+		return ShellQuote(string(value.Bytes()))
+	}
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		words := make([]string, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			words[i] = shellQuoteValue(value.Index(i).Interface())
+		}
+		return strings.Join(words, " ")
+	}
+
+	if v == nil {
+		return "''"
+	}
+	return ShellQuote(fmt.Sprint(v))
+}
+
+// ExecTemplate renders tmpl with Cmd and executes the result.
+func (b *BashProcess) ExecTemplate(tmpl string, data interface{}) error {
+	cmd, err := Cmd(tmpl, data)
+	if err != nil {
+		return err
+	}
+	return b.Exec(cmd)
+}
@@ -0,0 +1,38 @@
+package gocli
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCmdQuotesValues(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		data interface{}
+		want string
+	}{
+		{"safe", "echo {{.}}", "hello", "echo hello"},
+		{"spaces", "echo {{.}}", "hello world", "echo 'hello world'"},
+		{"quote", "echo {{.}}", "it's", `echo 'it'\''s'`},
+		{"empty", "echo {{.}}", "", "echo ''"},
+		{"raw", "echo {{.}}", Raw("-n -e"), "echo -n -e"},
+		{"raw function", "echo {{. | raw}}", "a b", "echo a b"},
+		{"slice", "echo {{.}}", []string{"a", "b c"}, "echo a 'b c'"},
+		{"bytes", "echo {{.}}", []byte("hi there"), "echo 'hi there'"},
+		{"raw message", "echo {{.}}", json.RawMessage(`{"a":1}`), `echo '{"a":1}'`},
+		{"byte slices", "echo {{.}}", [][]byte{[]byte("a"), []byte("b c")}, "echo a 'b c'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Cmd(tt.tmpl, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Cmd(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package gocli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long the CLI waits for the command and the cleanup hooks
+// to finish after a SIGINT or SIGTERM with EnableGracefulShutdown, unless it is changed
+// with SetShutdownTimeout.
+const DefaultShutdownTimeout = 5 * time.Second
+
+var (
+	processesMu sync.Mutex
+	processes   = map[*BashProcess]bool{}
+
+	// number of shutdown handlers that are listening for signals
+	listeners atomic.Int32
+
+	// shutdown handler of the running command, whose hooks run when the CLI exits on error
+	activeShutdown atomic.Pointer[shutdown]
+)
+
+// trackProcess registers a running process, so that signals can be forwarded to it
+func trackProcess(b *BashProcess) {
+	processesMu.Lock()
+	defer processesMu.Unlock()
+	processes[b] = true
+}
+
+func untrackProcess(b *BashProcess) {
+	processesMu.Lock()
+	defer processesMu.Unlock()
+	delete(processes, b)
+}
+
+// signalProcesses forwards sig to every running process. Processes that share the terminal
+// already receive SIGINT from it, so they only receive the other signals
+func signalProcesses(sig os.Signal) {
+	processesMu.Lock()
+	defer processesMu.Unlock()
+	for process := range processes {
+		if process.ownGroup || sig != os.Interrupt {
+			process.signal(sig)
+		}
+	}
+}
+
+// shutdown coordinates the cancellation of a command, its child processes and its
+// cleanup hooks when the CLI receives SIGINT or SIGTERM
+type shutdown struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+	timeout   time.Duration
+	hooks     []func()
+	signals   chan os.Signal
+	signaled  bool
+	finished  bool
+	listening bool
+	done      chan struct{}
+	hooksRan  atomic.Bool
+}
+
+func newShutdown(timeout time.Duration) *shutdown {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &shutdown{
+		ctx:     ctx,
+		cancel:  cancel,
+		timeout: timeout,
+		signals: make(chan os.Signal, 2),
+		done:    make(chan struct{}),
+	}
+}
+
+func (s *shutdown) onCleanup(hook func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// runHooks runs the cleanup hooks in LIFO order, at most once. A hook that exits the CLI
+// with fatal does not run the hooks again
+func (s *shutdown) runHooks() {
+	if !s.hooksRan.CompareAndSwap(false, true) {
+		return
+	}
+
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// runCleanup runs the cleanup hooks of the running command, if any, before the CLI exits
+func runCleanup() {
+	if s := activeShutdown.Load(); s != nil {
+		s.runHooks()
+	}
+}
+
+// shutdownListening reports whether a shutdown handler is listening for signals
+func shutdownListening() bool {
+	return listeners.Load() > 0
+}
+
+// start makes s the shutdown handler of the running command, so that its hooks run on
+// exit. It handles signals if listen is true
+func (s *shutdown) start(listen bool) {
+	activeShutdown.Store(s)
+	if listen {
+		s.listen()
+	}
+}
+
+// listen handles SIGINT and SIGTERM until the command has returned
+func (s *shutdown) listen() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listening || s.finished {
+		return
+	}
+	s.listening = true
+
+	listeners.Add(1)
+	signal.Notify(s.signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range s.signals {
+			s.handle(sig)
+		}
+	}()
+}
+
+func (s *shutdown) handle(sig os.Signal) {
+	code := 1
+	if number, ok := sig.(syscall.Signal); ok {
+		code = 128 + int(number)
+	}
+
+	s.mu.Lock()
+	force := s.signaled || s.finished
+	s.signaled = true
+	s.mu.Unlock()
+
+	restoreTerminals()
+	if force {
+		os.Exit(code)
+	}
+
+	// runs of watch mode are stopped by the CLI and not by the user
+	if !isWatchRun() {
+		fmt.Fprintln(os.Stderr, Yellow("Received %s. Shutting down (repeat to force)...", sig))
+	}
+	s.cancel()
+	signalProcesses(sig)
+
+	go func() {
+		deadline := time.After(s.timeout)
+		select {
+		case <-s.done:
+		case <-deadline:
+		}
+
+		hooksDone := make(chan struct{})
+		go func() {
+			s.runHooks()
+			close(hooksDone)
+		}()
+		select {
+		case <-hooksDone:
+		case <-deadline:
+			fmt.Fprintln(os.Stderr, Red("Shutdown timed out after %s", s.timeout))
+		}
+		os.Exit(code)
+	}()
+}
+
+// finish is called once the command has returned. If the CLI is shutting down because of
+// a signal, it blocks until the process exits. Otherwise it runs the cleanup hooks.
+func (s *shutdown) finish() {
+	s.mu.Lock()
+	signaled := s.signaled
+	listening := s.listening
+	s.finished = !signaled
+	s.mu.Unlock()
+
+	if signaled {
+		close(s.done)
+		select {}
+	}
+
+	s.runHooks()
+	if listening {
+		signal.Stop(s.signals)
+		listeners.Add(-1)
+	}
+	activeShutdown.CompareAndSwap(s, nil)
+	s.cancel()
+}
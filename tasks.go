@@ -0,0 +1,334 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tasksSpec is the root of a YAML tasks file:
+//
+//	name: tasks
+//	description: Project tasks
+//	commands:
+//	  build:
+//	    description: Build the binary
+//	    depends: [lint]
+//	    options:
+//	      - name: os
+//	        short: o
+//	        type: string
+//	        default: linux
+//	        description: Target operating system
+//	    arguments:
+//	      - name: output
+//	        type: string
+//	        required: true
+//	    run: GOOS={{.os}} go build -o {{.output}} ./...
+//	  lint:
+//	    run: go vet ./...
+//
+// The run template is rendered with Cmd, so every value is shell quoted. Options and
+// arguments can be referenced by their name (`{{.os}}`) or their Go field name (`{{.Os}}`).
+// Names with dashes are not valid in a template field, so "dry-run" is referenced as
+// `{{.dry_run}}`, `{{.DryRun}}` or `{{index . "dry-run"}}`.
+// Referencing a name that is not an option or argument is an error.
+// Dependencies are the space separated paths of other tasks, e.g. "db migrate".
+type tasksSpec struct {
+	Name        string               `yaml:"name"`
+	Description string               `yaml:"description"`
+	Commands    map[string]*taskSpec `yaml:"commands"`
+}
+
+type taskSpec struct {
+	Description string               `yaml:"description"`
+	Long        string               `yaml:"long"`
+	Aliases     []string             `yaml:"aliases"`
+	Options     []taskFieldSpec      `yaml:"options"`
+	Arguments   []taskFieldSpec      `yaml:"arguments"`
+	Depends     []string             `yaml:"depends"`
+	Run         string               `yaml:"run"`
+	Commands    map[string]*taskSpec `yaml:"commands"`
+}
+
+type taskFieldSpec struct {
+	Name        string      `yaml:"name"`
+	Short       string      `yaml:"short"`
+	Type        string      `yaml:"type"`
+	Required    bool        `yaml:"required"`
+	Default     interface{} `yaml:"default"`
+	Description string      `yaml:"description"`
+}
+
+type task struct {
+	path    string
+	spec    *taskSpec
+	command *Command
+}
+
+type tasks map[string]*task
+
+// NewTaskCli builds a CLI from a YAML tasks file (see AddTasks).
+func NewTaskCli(data []byte) *cli {
+	spec := parseTasksSpec(data)
+
+	name := spec.Name
+	if name == "" {
+		name = filepath.Base(os.Args[0])
+	}
+	root := &Command{Name: name, ShortDesc: spec.Description, LongDesc: spec.Description}
+	cli := NewCli(root)
+	cli.addTasks(root, spec)
+	return cli
+}
+
+// AddTasks adds the commands that are defined in a YAML tasks file as children of parent.
+// Every command runs its bash template after the tasks it depends on.
+func (cli *cli) AddTasks(parent *Command, data []byte) {
+	cli.addTasks(parent, parseTasksSpec(data))
+}
+
+func parseTasksSpec(data []byte) *tasksSpec {
+	spec := &tasksSpec{}
+	if err := yaml.Unmarshal(data, spec); err != nil {
+		fatal(newSetupError("Could not parse tasks file. %s", err.Error()))
+	}
+	return spec
+}
+
+func (cli *cli) addTasks(parent *Command, spec *tasksSpec) {
+	all := tasks{}
+	all.add(cli, parent, "", spec.Commands)
+
+	for _, t := range all {
+		for _, dep := range t.spec.Depends {
+			if _, exists := all[dep]; !exists {
+				fatal(newSetupError("Task '%s' depends on '%s', which does not exist.", t.path, dep))
+			}
+		}
+	}
+	for path := range all {
+		if _, err := all.order(path); err != nil {
+			fatal(newSetupError("%s", err.Error()))
+		}
+	}
+}
+
+func (all tasks) add(cli *cli, parent *Command, prefix string, specs map[string]*taskSpec) {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		spec := specs[name]
+		if spec == nil {
+			spec = &taskSpec{}
+		}
+		path := strings.TrimSpace(prefix + " " + name)
+
+		command, err := taskCommand(name, spec)
+		if err != nil {
+			fatal(newSetupError("Invalid task '%s'. %s", path, err.Error()))
+		}
+
+		t := &task{path: path, spec: spec, command: command}
+		if spec.Run != "" || len(spec.Depends) > 0 {
+			command.Behavior = func(ctx *Context) {
+				all.run(ctx, t)
+			}
+		}
+		all[path] = t
+
+		cli.AddChild(parent, command)
+		all.add(cli, command, path, spec.Commands)
+	}
+}
+
+func taskCommand(name string, spec *taskSpec) (*Command, error) {
+	command := &Command{
+		Name:      name,
+		Aliases:   spec.Aliases,
+		ShortDesc: spec.Description,
+		LongDesc:  spec.Long,
+	}
+	if command.LongDesc == "" {
+		command.LongDesc = spec.Description
+	}
+
+	if _, err := parseCmdTemplate(spec.Run); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if len(spec.Options) > 0 {
+		if command.Options, err = taskStruct(spec.Options); err != nil {
+			return nil, err
+		}
+	}
+	if len(spec.Arguments) > 0 {
+		if command.Arguments, err = taskStruct(spec.Arguments); err != nil {
+			return nil, err
+		}
+	}
+	return command, nil
+}
+
+func taskStruct(specs []taskFieldSpec) (interface{}, error) {
+	fields := make([]dynamicField, len(specs))
+	for i, spec := range specs {
+		if spec.Name == "" {
+			return nil, fmt.Errorf("Every option and argument needs a name")
+		}
+
+		kind := spec.Type
+		if kind == "" {
+			kind = "string"
+		}
+		if _, ok := dynamicTypes[kind]; !ok {
+			return nil, fmt.Errorf("Invalid type '%s' for '%s'. Allowed types are string, bool, int and float", kind, spec.Name)
+		}
+
+		fields[i] = dynamicField{
+			name:        spec.Name,
+			short:       spec.Short,
+			kind:        kind,
+			required:    spec.Required,
+			description: spec.Description,
+		}
+		if spec.Default != nil {
+			fields[i].def = fmt.Sprint(spec.Default)
+		}
+	}
+	return dynamicStruct(fields)
+}
+
+// order returns the dependencies of path, in the order they have to run
+func (all tasks) order(path string) ([]*task, error) {
+	order := []*task{}
+	state := map[string]int{}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	var visit func(path string, chain []string) error
+	visit = func(path string, chain []string) error {
+		chain = append(chain, path)
+		switch state[path] {
+		case visiting:
+			return fmt.Errorf("Tasks have a circular dependency: %s", strings.Join(chain, " -> "))
+		case visited:
+			return nil
+		}
+
+		state[path] = visiting
+		for _, dep := range all[path].spec.Depends {
+			if err := visit(dep, chain); err != nil {
+				return err
+			}
+		}
+		state[path] = visited
+		order = append(order, all[path])
+		return nil
+	}
+
+	if err := visit(path, nil); err != nil {
+		return nil, err
+	}
+	return order[:len(order)-1], nil
+}
+
+func (all tasks) run(ctx *Context, t *task) {
+	deps, err := all.order(t.path)
+	if err != nil {
+		fatal(newError("%s", err.Error()))
+	}
+
+	for _, dep := range deps {
+		data, err := dep.defaults()
+		if err != nil {
+			fatal(newError("%s", err.Error()))
+		}
+		fmt.Fprintln(os.Stderr, Cyan("[%s]", dep.path))
+		dep.exec(ctx, data)
+	}
+
+	data := map[string]interface{}{}
+	for _, option := range ctx.options {
+		t.addData(data, t.spec.Options, option.long, option.value)
+	}
+	for _, argument := range ctx.arguments {
+		t.addData(data, t.spec.Arguments, argument.name, argument.value)
+	}
+	t.exec(ctx, data)
+}
+
+// defaults returns the template data of a task that runs as a dependency
+func (t *task) defaults() (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	for _, option := range optionsMapToArray(buildOptionsMap(t.command)) {
+		if option.required && t.hasNoDefault(t.spec.Options, option.long) {
+			return nil, fmt.Errorf("Cannot run task '%s' as a dependency. Its option '--%s' is required and has no default", t.path, option.long)
+		}
+		t.addData(data, t.spec.Options, option.long, option.value)
+	}
+	for _, argument := range buildArguments(t.command) {
+		if argument.required && t.hasNoDefault(t.spec.Arguments, argument.name) {
+			return nil, fmt.Errorf("Cannot run task '%s' as a dependency. Its argument '%s' is required and has no default", t.path, argument.name)
+		}
+		t.addData(data, t.spec.Arguments, argument.name, argument.value)
+	}
+	return data, nil
+}
+
+func (t *task) hasNoDefault(specs []taskFieldSpec, name string) bool {
+	if spec := findFieldSpec(specs, name); spec != nil {
+		return spec.Default == nil
+	}
+	return true
+}
+
+// findFieldSpec returns the spec of the option or argument name. The name in the tasks
+// file can differ from the parsed name, e.g. "dryRun" is parsed as "dry-run"
+func findFieldSpec(specs []taskFieldSpec, name string) *taskFieldSpec {
+	for i, spec := range specs {
+		if convertToCamelCase(spec.Name) == convertToCamelCase(name) {
+			return &specs[i]
+		}
+	}
+	return nil
+}
+
+// addData adds an option or argument to the template data, under every name it can be
+// referenced by
+func (t *task) addData(data map[string]interface{}, specs []taskFieldSpec, name string, value interface{}) {
+	data[name] = value
+	data[strings.ReplaceAll(name, "-", "_")] = value
+	data[convertToCamelCase(name)] = value
+	if spec := findFieldSpec(specs, name); spec != nil {
+		data[spec.Name] = value
+	}
+}
+
+func (t *task) exec(ctx *Context, data map[string]interface{}) {
+	if t.spec.Run == "" {
+		return
+	}
+
+	// a misspelled name is an error instead of an empty argument
+	cmd, err := renderCmd(t.spec.Run, data, "missingkey=error")
+	if err != nil {
+		fatal(newError("Task '%s'. %s", t.path, err.Error()))
+	}
+	if err := ctx.Runner().Exec(cmd); err != nil {
+		printError(newError("Task '%s' failed. %s", t.path, err.Error()))
+		exit(max(exitCode(err), 1))
+	}
+}
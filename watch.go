@@ -0,0 +1,262 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	watchDebounce     = 200 * time.Millisecond
+	watchPollInterval = 500 * time.Millisecond
+)
+
+// EnableWatch adds the built-in options "--watch <glob>" and "--watch-clear" to every
+// command. In watch mode the command runs again whenever a file matching the glob changes.
+// Every run is a child process of the CLI, in its own process group and without stdin, so
+// that a run that fails only prints its error. A run that is still in progress is stopped
+// first: its process group receives SIGTERM, and SIGKILL after the shutdown timeout.
+func (cli *cli) EnableWatch() {
+	cli.addBuiltin(watchOption, "", reflect.String, "Re-run the command whenever a file matching this glob changes (\"**\" matches any directory)")
+	cli.addBuiltin(watchClearOption, "", reflect.Bool, "Clear the screen before every run in watch mode")
+}
+
+// watchRunEnv is set in the environment of the runs of watch mode
+const watchRunEnv = "GOCLI_WATCH_RUN"
+
+// isWatchRun reports whether the CLI is a run of watch mode
+func isWatchRun() bool {
+	return os.Getenv(watchRunEnv) != ""
+}
+
+func watch(ctx *Context, pattern string, clear bool) {
+	stop := make(chan struct{})
+	defer close(stop)
+	changes := watchFiles(pattern, stop)
+
+	// the runs are in their own process group, so they don't receive the signals of the
+	// terminal. The CLI stops them when it is interrupted
+	timeout := DefaultShutdownTimeout
+	if ctx.shutdown != nil {
+		ctx.shutdown.listen()
+		timeout = ctx.shutdown.timeout
+	}
+
+	// the CLI can exit on a signal before the run is stopped
+	var mu sync.Mutex
+	var current *exec.Cmd
+	ctx.OnCleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if current != nil {
+			signalProcess(current.Process, true, syscall.SIGKILL)
+		}
+	})
+
+	parent := ctx.Context()
+	for {
+		if clear {
+			fmt.Print("\033[H\033[2J")
+		}
+
+		run, err := startWatchRun()
+		if err != nil {
+			fatal(newError("Could not start the command. %s", err.Error()))
+		}
+		mu.Lock()
+		current = run
+		mu.Unlock()
+
+		done := make(chan struct{})
+		var runErr error
+		go func() {
+			defer close(done)
+			runErr = run.Wait()
+		}()
+
+		select {
+		case <-done:
+			if runErr != nil {
+				fmt.Fprintln(os.Stderr, Red("Exited with code %d. Waiting for changes...", exitCode(runErr)))
+			}
+			select {
+			case <-parent.Done():
+				return
+			case path := <-changes:
+				debounce(changes)
+				fmt.Fprintln(os.Stderr, Cyan("%s changed. Restarting...", path))
+			}
+		case <-parent.Done():
+			stopWatchRun(run, done, timeout)
+			return
+		case path := <-changes:
+			debounce(changes)
+			stopWatchRun(run, done, timeout)
+			fmt.Fprintln(os.Stderr, Cyan("%s changed. Restarting...", path))
+		}
+	}
+}
+
+// startWatchRun runs the CLI again with the same arguments, as the leader of a new process
+// group
+func startWatchRun() (*exec.Cmd, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), watchRunEnv+"=1")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	configureProcess(cmd, true)
+	return cmd, cmd.Start()
+}
+
+// stopWatchRun sends SIGTERM to the process group of run, and SIGKILL if it is still running
+// after timeout. done must be closed once run has exited
+func stopWatchRun(run *exec.Cmd, done <-chan struct{}, timeout time.Duration) {
+	signalProcess(run.Process, true, syscall.SIGTERM)
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		signalProcess(run.Process, true, syscall.SIGKILL)
+		<-done
+	}
+	// processes that ignored SIGTERM can outlive the leader of the group
+	signalProcess(run.Process, true, syscall.SIGKILL)
+}
+
+// debounce waits until there were no changes for watchDebounce
+func debounce(changes <-chan string) {
+	timer := time.NewTimer(watchDebounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-changes:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(watchDebounce)
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+// watchFiles sends the paths of the files matching pattern that change, until stop is
+// closed. It uses inotify on Linux, and polls the files otherwise
+func watchFiles(pattern string, stop <-chan struct{}) <-chan string {
+	pattern = filepath.Clean(pattern)
+	if changes, err := notifyWatch(pattern, stop); err == nil {
+		return changes
+	}
+	return pollWatch(pattern, stop)
+}
+
+func pollWatch(pattern string, stop <-chan struct{}) <-chan string {
+	changes := make(chan string)
+
+	snapshot := func() map[string]string {
+		files, _ := globFiles([]string{pattern})
+		state := map[string]string{}
+		for _, file := range files {
+			if info, err := os.Stat(file); err == nil {
+				state[file] = fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+			}
+		}
+		return state
+	}
+
+	go func() {
+		previous := snapshot()
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			current := snapshot()
+			changed := []string{}
+			for file, state := range current {
+				if previous[file] != state {
+					changed = append(changed, file)
+				}
+			}
+			for file := range previous {
+				if _, exists := current[file]; !exists {
+					changed = append(changed, file)
+				}
+			}
+			previous = current
+
+			for _, file := range changed {
+				select {
+				case changes <- file:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	return changes
+}
+
+// watchRoot returns the directory that contains every match of pattern, and how deep
+// below it matches can be. A depth of -1 means unlimited
+func watchRoot(pattern string) (string, int) {
+	segments := strings.Split(pattern, string(filepath.Separator))
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "*?[") {
+			root := filepath.Join(segments[:i]...)
+			if strings.HasPrefix(pattern, string(filepath.Separator)) {
+				root = string(filepath.Separator) + root
+			}
+			if root == "" {
+				root = "."
+			}
+			if strings.Contains(pattern, "**") {
+				return root, -1
+			}
+			return root, len(segments) - i - 1
+		}
+	}
+	return filepath.Dir(pattern), 0
+}
+
+// matchGlob reports whether path matches pattern. "**" matches any number of directories
+func matchGlob(pattern, path string) bool {
+	return matchSegments(strings.Split(filepath.Clean(pattern), string(filepath.Separator)), strings.Split(filepath.Clean(path), string(filepath.Separator)))
+}
+
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(path) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
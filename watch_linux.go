@@ -0,0 +1,91 @@
+package gocli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const notifyMask = unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MODIFY | unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+
+// notifyWatch watches the files matching pattern with inotify
+func notifyWatch(pattern string, stop <-chan struct{}) (<-chan string, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	// a non blocking *os.File uses the runtime poller, so Close unblocks Read
+	file := os.NewFile(uintptr(fd), "inotify")
+
+	root, maxDepth := watchRoot(pattern)
+	dirs := map[int]string{}
+	addDir := func(dir string) {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || !info.IsDir() {
+				return nil
+			}
+			if maxDepth >= 0 && depth(root, path) > maxDepth {
+				return filepath.SkipDir
+			}
+			if wd, err := unix.InotifyAddWatch(fd, path, notifyMask); err == nil {
+				dirs[wd] = path
+			}
+			return nil
+		})
+	}
+	addDir(root)
+	if len(dirs) == 0 {
+		file.Close()
+		return nil, os.ErrNotExist
+	}
+
+	changes := make(chan string)
+	go func() {
+		<-stop
+		file.Close()
+	}()
+
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameStart := offset + unix.SizeofInotifyEvent
+				name := string(bytes.TrimRight(buf[nameStart:nameStart+int(event.Len)], "\x00"))
+				offset = nameStart + int(event.Len)
+
+				path := filepath.Join(dirs[int(event.Wd)], name)
+				if event.Mask&unix.IN_ISDIR != 0 && event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+					addDir(path)
+				}
+				if !matchGlob(pattern, path) {
+					continue
+				}
+
+				select {
+				case changes <- path:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
+
+func depth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
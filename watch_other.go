@@ -0,0 +1,11 @@
+//go:build !linux
+
+package gocli
+
+import (
+	"fmt"
+)
+
+func notifyWatch(pattern string, stop <-chan struct{}) (<-chan string, error) {
+	return nil, fmt.Errorf("File notifications are only supported on Linux")
+}